@@ -0,0 +1,49 @@
+package gpt3
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// azureConfig holds the settings for talking to an Azure OpenAI resource, see WithAzure
+type azureConfig struct {
+	deployment string
+	apiVersion string
+}
+
+// azureDeploymentPaths are the paths that Azure serves from a model deployment rather than from the resource
+var azureDeploymentPaths = []string{
+	"/completions",
+	"/chat/completions",
+	"/embeddings",
+	"/edits",
+	"/images/",
+	"/audio/",
+}
+
+// url maps an OpenAI API path onto the Azure url scheme, routing model calls to the deployment and adding
+// the api-version query parameter
+func (a *azureConfig) url(baseURL, path string) (string, error) {
+	if strings.HasPrefix(path, "/engines/") {
+		// the engine is chosen by the deployment, so "/engines/{engine}/completions" becomes "/completions"
+		if i := strings.Index(path[len("/engines/"):], "/"); i >= 0 {
+			path = path[len("/engines/")+i:]
+		}
+	}
+	for _, p := range azureDeploymentPaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			path = fmt.Sprintf("/deployments/%s%s", url.PathEscape(a.deployment), path)
+			break
+		}
+	}
+
+	u, err := url.Parse(baseURL + path)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("api-version", a.apiVersion)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,177 @@
+package gpt3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	// embeddingsBatchMaxInputs is the most inputs the embeddings API accepts in one request
+	embeddingsBatchMaxInputs = 2048
+	// embeddingsBatchMaxTokens is the most tokens of all inputs together the embeddings API accepts in one request
+	embeddingsBatchMaxTokens = 300000
+	// embeddingInputMaxTokens is the most tokens of a single input of models without a known context window
+	embeddingInputMaxTokens = 8191
+	// embeddingsBatchConcurrency is how many requests of EmbeddingsBatch are sent at once
+	embeddingsBatchConcurrency = 4
+)
+
+// CompletionBatch runs each of the requests with the default engine, at most concurrency at a time. Responses and
+// errors are returned at the same index as their request, and a failed request doesn't stop the others. Once ctx is
+// done no more requests are started, and those not started have ctx.Err() as their error.
+func (c *client) CompletionBatch(
+	ctx context.Context,
+	requests []CompletionRequest,
+	concurrency int) ([]*CompletionResponse, []error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	responses := make([]*CompletionResponse, len(requests))
+	errs := make([]error, len(requests))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range requests {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for ; i < len(requests); i++ {
+				errs[i] = err
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responses[i], errs[i] = c.Completion(ctx, requests[i])
+		}(i)
+	}
+	wg.Wait()
+	return responses, errs
+}
+
+// EmbeddingsBatch gets the embeddings of inputs with model, splitting them into as many requests as needed to stay
+// under the input count and token limits of the embeddings API. The requests are sent a few at a time, and the first
+// one to fail stops the others, as does ctx being done, in which case ctx.Err() is returned. The embeddings are
+// returned in the order of inputs, with Index being the index of their input, and Usage is the total of all requests.
+// A response that doesn't have exactly one embedding for each of its inputs is an error.
+func (c *client) EmbeddingsBatch(ctx context.Context, model string, inputs []string) (*EmbeddingsResponse, error) {
+	if err := c.checkModel(model); err != nil {
+		return nil, err
+	}
+	batches, err := splitEmbeddingsInputs(model, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	output := &EmbeddingsResponse{Object: "list", Data: make([]Embedding, len(inputs))}
+	sem := make(chan struct{}, embeddingsBatchConcurrency)
+	started := 0
+	for _, batch := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		started++
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			rsp, err := c.Embeddings(ctx, EmbeddingsRequest{Input: inputs[start:end], Model: model})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				err = checkEmbeddingIndexes(rsp.Data, end-start)
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			for _, embedding := range rsp.Data {
+				embedding.Index += start
+				output.Data[embedding.Index] = embedding
+			}
+			output.Usage.PromptTokens += rsp.Usage.PromptTokens
+			output.Usage.TotalTokens += rsp.Usage.TotalTokens
+		}(batch[0], batch[1])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// ctx was done before all of the batches were started
+	if started < len(batches) {
+		return nil, ctx.Err()
+	}
+	return output, nil
+}
+
+// checkEmbeddingIndexes returns an error unless embeddings has exactly one embedding for each of the count inputs of
+// a request
+func checkEmbeddingIndexes(embeddings []Embedding, count int) error {
+	if len(embeddings) != count {
+		return fmt.Errorf("expected %d embeddings but got %d", count, len(embeddings))
+	}
+	seen := make([]bool, count)
+	for _, embedding := range embeddings {
+		if embedding.Index < 0 || embedding.Index >= count {
+			return fmt.Errorf("embedding index %d is out of range for %d inputs", embedding.Index, count)
+		}
+		if seen[embedding.Index] {
+			return fmt.Errorf("embedding index %d is repeated", embedding.Index)
+		}
+		seen[embedding.Index] = true
+	}
+	return nil
+}
+
+// splitEmbeddingsInputs returns the start and end index of each batch of inputs that fits in one embeddings request
+func splitEmbeddingsInputs(model string, inputs []string) ([][2]int, error) {
+	inputLimit, ok := ModelContextWindow(model)
+	if !ok {
+		inputLimit = embeddingInputMaxTokens
+	}
+
+	var batches [][2]int
+	start, batchTokens := 0, 0
+	for i, input := range inputs {
+		tokens, err := CountTokens(model, input)
+		if err != nil {
+			return nil, err
+		}
+		if tokens > inputLimit {
+			return nil, fmt.Errorf("input %d is %d tokens which exceeds the %d token limit of model %q",
+				i, tokens, inputLimit, model)
+		}
+		if i > start && (i-start >= embeddingsBatchMaxInputs || batchTokens+tokens > embeddingsBatchMaxTokens) {
+			batches = append(batches, [2]int{start, i})
+			start, batchTokens = i, 0
+		}
+		batchTokens += tokens
+	}
+	if start < len(inputs) {
+		batches = append(batches, [2]int{start, len(inputs)})
+	}
+	return batches, nil
+}
@@ -0,0 +1,239 @@
+package gpt3_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+	"golang.org/x/net/context"
+)
+
+func TestCompletionBatch(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		var request gpt3.CompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request.Prompt[0] == "fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"bad prompt","type":"invalid_request_error"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"text":"echo %s"}]}`, request.Prompt[0])
+	}))
+	defer server.Close()
+
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+
+	var requests []gpt3.CompletionRequest
+	for i := 0; i < 10; i++ {
+		prompt := fmt.Sprint(i)
+		if i == 4 {
+			prompt = "fail"
+		}
+		requests = append(requests, gpt3.CompletionRequest{Prompt: []string{prompt}})
+	}
+
+	responses, errs := client.CompletionBatch(context.Background(), requests, 3)
+	assert.Len(t, responses, 10)
+	assert.Len(t, errs, 10)
+	for i := range requests {
+		if i == 4 {
+			assert.Nil(t, responses[i])
+			assert.EqualError(t, errs[i], "[400:invalid_request_error] bad prompt")
+			continue
+		}
+		assert.NoError(t, errs[i])
+		assert.Equal(t, fmt.Sprintf("echo %d", i), responses[i].Choices[0].Text)
+	}
+	assert.LessOrEqual(t, int(atomic.LoadInt32(&maxInFlight)), 3)
+	assert.Greater(t, int(atomic.LoadInt32(&maxInFlight)), 1)
+}
+
+func TestCompletionBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&started, 1) == 2 {
+			cancel()
+		}
+		fmt.Fprint(w, `{"choices":[{"text":"ok"}]}`)
+	}))
+	defer server.Close()
+
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+	requests := make([]gpt3.CompletionRequest, 5)
+	for i := range requests {
+		requests[i] = gpt3.CompletionRequest{Prompt: []string{"test"}}
+	}
+
+	_, errs := client.CompletionBatch(ctx, requests, 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&started))
+	assert.NoError(t, errs[0])
+	for i := 2; i < len(requests); i++ {
+		assert.Equal(t, context.Canceled, errs[i])
+	}
+}
+
+func TestEmbeddingsBatch(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var request gpt3.EmbeddingsRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		rsp := gpt3.EmbeddingsResponse{Object: "list"}
+		for i, input := range request.Input {
+			value, _ := strconv.Atoi(input)
+			rsp.Data = append(rsp.Data, gpt3.Embedding{Object: "embedding", Embedding: []float32{float32(value)}, Index: i})
+		}
+		rsp.Usage.PromptTokens = len(request.Input)
+		rsp.Usage.TotalTokens = len(request.Input)
+		_ = json.NewEncoder(w).Encode(rsp)
+	}))
+	defer server.Close()
+
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+
+	inputs := make([]string, 2050)
+	for i := range inputs {
+		inputs[i] = strconv.Itoa(i)
+	}
+	rsp, err := client.EmbeddingsBatch(context.Background(), gpt3.TextEmbeddingAda002, inputs)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Len(t, rsp.Data, len(inputs))
+	for i, embedding := range rsp.Data {
+		assert.Equal(t, i, embedding.Index)
+		assert.Equal(t, []float32{float32(i)}, embedding.Embedding)
+	}
+	assert.Equal(t, len(inputs), rsp.Usage.TotalTokens)
+
+	_, err = client.EmbeddingsBatch(context.Background(), gpt3.TextEmbeddingAda002,
+		[]string{"short", strings.Repeat(" hello", 9000)})
+	assert.EqualError(t, err, `input 1 is 9000 tokens which exceeds the 8191 token limit of model "text-embedding-ada-002"`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestEmbeddingsBatchErrors(t *testing.T) {
+	var indexes string
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(w, `{"object":"list","data":[{"index":%s}]}`, indexes)
+	}))
+	defer server.Close()
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+
+	for indexesJSON, expected := range map[string]string{
+		`0},{"index":2`:  "embedding index 2 is out of range for 2 inputs",
+		`-1},{"index":0`: "embedding index -1 is out of range for 2 inputs",
+		`1},{"index":1`:  "embedding index 1 is repeated",
+		`0`:              "expected 2 embeddings but got 1",
+	} {
+		indexes = indexesJSON
+		_, err := client.EmbeddingsBatch(context.Background(), gpt3.TextEmbeddingAda002, []string{"a", "b"})
+		assert.EqualError(t, err, expected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	atomic.StoreInt32(&requests, 0)
+	_, err := client.EmbeddingsBatch(ctx, gpt3.TextEmbeddingAda002, []string{"a", "b"})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
+
+// countingServer starts a server answering model requests that counts the connections made to it
+func countingServer(delay time.Duration) (*httptest.Server, *int32) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		fmt.Fprint(w, `{"object":"list","data":[]}`)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	return server, &conns
+}
+
+// sendConcurrently sends n requests with client at the same time and waits for them to complete
+func sendConcurrently(client gpt3.Client, n int) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = client.Models(context.Background())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithTransportTuning(t *testing.T) {
+	server, conns := countingServer(20 * time.Millisecond)
+	defer server.Close()
+
+	// the idle connections of the first round are all kept for the second
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL), gpt3.WithTransportTuning(100, 8, time.Minute))
+	sendConcurrently(client, 8)
+	sendConcurrently(client, 8)
+	assert.LessOrEqual(t, int(atomic.LoadInt32(conns)), 8)
+
+	// by default only 2 are kept, so the second round opens new connections
+	atomic.StoreInt32(conns, 0)
+	client = gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+	sendConcurrently(client, 8)
+	sendConcurrently(client, 8)
+	assert.Greater(t, int(atomic.LoadInt32(conns)), 8)
+}
+
+func BenchmarkParallelRequests(b *testing.B) {
+	server, conns := countingServer(0)
+	defer server.Close()
+
+	for _, bc := range []struct {
+		name    string
+		options []gpt3.ClientOption
+	}{
+		{"default", nil},
+		{"tuned", []gpt3.ClientOption{gpt3.WithTransportTuning(100, 100, time.Minute)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			atomic.StoreInt32(conns, 0)
+			client := gpt3.NewClient("test-key", append(bc.options, gpt3.WithBaseURL(server.URL))...)
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := client.Models(context.Background()); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+			// connections opened per request, which the tuned pool keeps close to zero
+			b.ReportMetric(float64(atomic.LoadInt32(conns))/float64(b.N), "conns/op")
+		})
+	}
+}
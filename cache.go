@@ -0,0 +1,143 @@
+package gpt3
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// Cache stores responses for WithCache. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored for key, and whether there is one
+	Get(key string) ([]byte, bool)
+	// Set stores value for key, replacing any value already stored
+	Set(key string, value []byte)
+}
+
+// LRUCache is an in-memory Cache that holds a fixed number of values, evicting the least recently used value when
+// it's full
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// lruEntry is a value of an LRUCache along with its key, for removing it from the map when it's evicted
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRUCache returns an empty LRUCache holding up to capacity values. A capacity below 1 is treated as 1.
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return copyBytes(element.Value.(*lruEntry).value), true
+}
+
+func (c *LRUCache) Set(key string, value []byte) {
+	value = copyBytes(value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruEntry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of values in the cache
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// copyBytes returns a copy of value, so the values in an LRUCache aren't changed by callers modifying the slices they
+// passed to Set or got from Get
+func copyBytes(value []byte) []byte {
+	if value == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(value)), value...)
+}
+
+// cacheKey returns the key of the response to request sent to path with ctx, or "" when it can't be encoded. The key
+// is a hash of the organization and project the request is sent for, so they don't share responses, and of the
+// request JSON, which encoding/json writes with fields in a fixed order.
+func (c *client) cacheKey(ctx context.Context, path string, request interface{}) string {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.New()
+	for _, part := range []string{c.requestOrg(ctx), c.idProject, path} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cachedResponse decodes the response cached for key into v, returning the raw response and whether it was cached
+func (c *client) cachedResponse(key string, v interface{}) (json.RawMessage, bool) {
+	if c.cache == nil || key == "" {
+		return nil, false
+	}
+	data, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// cacheResponse stores the raw response for key
+func (c *client) cacheResponse(key string, raw json.RawMessage) {
+	if c.cache == nil || key == "" {
+		return
+	}
+	c.cache.Set(key, raw)
+}
+
+// isCacheableCompletion reports whether request is deterministic enough for its response to be cached, which is when
+// its temperature is 0 or it has a seed
+func isCacheableCompletion(request CompletionRequest) bool {
+	return (request.Temperature != nil && *request.Temperature == 0) || request.Seed != nil
+}
+
+// isCacheableChatCompletion reports whether request is deterministic enough for its response to be cached. A
+// temperature of 0 isn't sent, so the API uses its default temperature, which leaves a seed as the only way.
+func isCacheableChatCompletion(request ChatCompletionRequest) bool {
+	return request.Seed != nil
+}
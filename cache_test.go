@@ -0,0 +1,42 @@
+package gpt3_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := gpt3.NewLRUCache(2)
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+
+	// reading a makes b the least recently used
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	cache.Set("c", []byte("3"))
+	assert.Equal(t, 2, cache.Len())
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+
+	cache.Set("a", []byte("4"))
+	value, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("4"), value)
+	value, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("3"), value)
+	assert.Equal(t, 2, cache.Len())
+
+	// changing the slices passed to Set or returned by Get doesn't change the cached value
+	set := []byte("5")
+	cache.Set("d", set)
+	set[0] = 'x'
+	value, _ = cache.Get("d")
+	value[0] = 'y'
+	value, _ = cache.Get("d")
+	assert.Equal(t, []byte("5"), value)
+}
@@ -1,8 +1,13 @@
 package gpt3
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 // ClientOption are options that can be passed when creating a new client
@@ -16,6 +21,15 @@ func WithOrg(id string) ClientOption {
 	}
 }
 
+// WithProject is a client option that sets the project ID sent in the OpenAI-Project header, for use with
+// project scoped api keys
+func WithProject(id string) ClientOption {
+	return func(c *client) error {
+		c.idProject = id
+		return nil
+	}
+}
+
 // WithDefaultEngine is a client option that allows you to override the default engine of the client
 func WithDefaultEngine(engine string) ClientOption {
 	return func(c *client) error {
@@ -24,6 +38,26 @@ func WithDefaultEngine(engine string) ClientOption {
 	}
 }
 
+// WithDefaultModel is a client option that allows you to override the model used for chat completions when
+// the request doesn't set one. The default is GPT3Dot5Turbo
+func WithDefaultModel(model string) ClientOption {
+	return func(c *client) error {
+		c.defaultModel = model
+		return nil
+	}
+}
+
+// WithModelValidation is a client option that sets whether the models of completion, chat completion and embeddings
+// requests are checked with Model.Valid before they're sent, failing with ErrUnknownModel when they aren't. It's
+// enabled by default for the OpenAI API and disabled when WithBaseURL or WithAzure is used. Disable it to use a model
+// released after this package.
+func WithModelValidation(enabled bool) ClientOption {
+	return func(c *client) error {
+		c.modelValidation = &enabled
+		return nil
+	}
+}
+
 // WithUserAgent is a client option that allows you to override the default user agent of the client
 func WithUserAgent(userAgent string) ClientOption {
 	return func(c *client) error {
@@ -32,16 +66,63 @@ func WithUserAgent(userAgent string) ClientOption {
 	}
 }
 
-// WithBaseURL is a client option that allows you to override the default base url of the client.
-// The default base url is "https://api.openai.com/v1"
+// WithBaseURL is a client option that allows you to override the default base url of the client,
+// for example to point it at a self-hosted gateway. All requests are built from this url, and a
+// trailing slash is ignored. The default base url is "https://api.openai.com/v1"
 func WithBaseURL(baseURL string) ClientOption {
 	return func(c *client) error {
-		c.baseURL = baseURL
+		c.baseURL = strings.TrimRight(baseURL, "/")
+		return nil
+	}
+}
+
+// WithAzure is a client option for using an Azure OpenAI resource instead of the OpenAI API. Requests are sent to
+// https://{resource}.openai.azure.com/openai, with model calls such as completions and chat completions routed to
+// the given deployment. The api key is sent in the api-key header and apiVersion as the api-version query parameter.
+// The engine or model of a request is chosen by the deployment.
+func WithAzure(resource, deployment, apiVersion string) ClientOption {
+	return func(c *client) error {
+		c.baseURL = fmt.Sprintf("https://%s.openai.azure.com/openai", resource)
+		c.azure = &azureConfig{
+			deployment: deployment,
+			apiVersion: apiVersion,
+		}
+		return nil
+	}
+}
+
+// WithHeader is a client option that adds a header to every request, for example a token required by a gateway.
+// It can be passed more than once. Headers are applied after the client's own headers, so setting one such as
+// Authorization or Content-Type replaces the default value.
+func WithHeader(key, value string) ClientOption {
+	return func(c *client) error {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Add(key, value)
 		return nil
 	}
 }
 
-// WithHTTPClient allows you to override the internal http.Client used
+// WithHeaders is a client option that adds all of the given headers to every request, in the same way as WithHeader.
+func WithHeaders(headers http.Header) ClientOption {
+	return func(c *client) error {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		for key, values := range headers {
+			for _, value := range values {
+				c.headers.Add(key, value)
+			}
+		}
+		return nil
+	}
+}
+
+// WithHTTPClient allows you to override the internal http.Client used, for example to supply a custom
+// transport, proxy, TLS config or connection pooling. The client is used as is: its Timeout is never
+// modified, and it takes precedence over WithTimeout, WithTransportTuning and WithInsecureSkipVerify regardless of
+// the order the options are passed in.
 func WithHTTPClient(httpClient *http.Client) ClientOption {
 	return func(c *client) error {
 		c.httpClient = httpClient
@@ -49,12 +130,161 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// WithTransportTuning is a client option that sizes the connection pool of the client, for sending many requests
+// concurrently. maxIdleConns is the most idle connections kept in total, maxIdleConnsPerHost the most kept to the API
+// host, and idleTimeout how long an idle connection is kept before closing it. Zero values mean the same as for
+// http.Transport. The default transport keeps only 2 idle connections per host, so concurrent requests beyond that
+// keep opening new connections. It has no effect when WithHTTPClient is used, whose transport is used as is.
+func WithTransportTuning(maxIdleConns, maxIdleConnsPerHost int, idleTimeout time.Duration) ClientOption {
+	return func(c *client) error {
+		c.transportTuning = &transportTuning{
+			maxIdleConns:        maxIdleConns,
+			maxIdleConnsPerHost: maxIdleConnsPerHost,
+			idleTimeout:         idleTimeout,
+		}
+		return nil
+	}
+}
+
+// transportTuning is the connection pool configuration of WithTransportTuning
+type transportTuning struct {
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleTimeout         time.Duration
+}
+
 // WithTimeout is a client option that allows you to override the default timeout duration of requests
-// for the client. The default is 30 seconds. If you are overriding the http client as well, just include
-// the timeout there.
+// for the client. The default is 30 seconds. The timeout only applies to calls whose context has no deadline, so a
+// call can be given a longer or shorter time with context.WithTimeout. Each retry gets the full timeout. For
+// streaming calls the timeout applies to connecting and waiting for the response to start, but not to how long the
+// stream is read for. If you are overriding the http client as well, just include the timeout there, as WithTimeout
+// is ignored when WithHTTPClient is used.
 func WithTimeout(timeout time.Duration) ClientOption {
 	return func(c *client) error {
-		c.httpClient.Timeout = timeout
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithLogger is a client option that calls logger with every request that is sent and its response, for debugging.
+// resp and body are nil when the request failed. The response body is buffered and passed in body, except for
+// successful streaming responses where body is nil. The request body can be read with req.GetBody. Credentials in
+// the Authorization and api-key headers are redacted.
+func WithLogger(logger func(req *http.Request, resp *http.Response, body []byte)) ClientOption {
+	return func(c *client) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithObserver is a client option that calls observer after every request completes, for recording metrics. endpoint
+// is the API path the request was made to, such as "/chat/completions", and status is the response status code, or 0
+// when no response was received. dur is the time taken to receive the response headers, so for streams it doesn't
+// include reading the stream. Each retry is observed separately.
+func WithObserver(observer func(endpoint string, status int, dur time.Duration)) ClientOption {
+	return func(c *client) error {
+		c.observer = observer
+		return nil
+	}
+}
+
+// WithRateLimit is a client option that limits the client to rps requests per second on average, with bursts of up
+// to burst requests, across all of its methods. Requests wait until they are allowed, or return ctx.Err() if their
+// context is done first. Retries are limited too. The default is no limit.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *client) error {
+		c.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		return nil
+	}
+}
+
+// WithRetry is a client option that retries requests failing with a 429 (rate limited) or 5xx status up to
+// maxRetries times, waiting the delay of WithBackoffStrategy between attempts. When a rate limited
+// response includes a Retry-After header, that duration is waited instead. Retries stop early if the request
+// context is done. The default is not to retry.
+func WithRetry(maxRetries int) ClientOption {
+	return func(c *client) error {
+		c.maxRetries = maxRetries
+		return nil
+	}
+}
+
+// WithBackoffStrategy is a client option that sets how long WithRetry waits before retrying after the given (zero
+// based) attempt failed, such as a fixed schedule or decorrelated jitter. A zero or negative duration retries
+// immediately. A Retry-After header of a rate limited response takes precedence. The default is DefaultBackoff, which
+// can be wrapped to adjust its delays. A nil strategy restores the default.
+func WithBackoffStrategy(backoff func(attempt int) time.Duration) ClientOption {
+	return func(c *client) error {
+		if backoff == nil {
+			backoff = DefaultBackoff
+		}
+		c.backoff = backoff
+		return nil
+	}
+}
+
+// WithIdempotencyKey is a client option that sends a unique Idempotency-Key header with every POST request, so that a
+// request which is sent again by WithRetry isn't acted on twice, such as creating two fine-tunes or images. The key is
+// generated by gen once per call and reused for all of its retries. When gen is nil random keys are used. A key is
+// honored for 24 hours, so gen must not repeat keys within that time.
+func WithIdempotencyKey(gen func() string) ClientOption {
+	return func(c *client) error {
+		if gen == nil {
+			c.idempotencyKey = newIdempotencyKey
+			return nil
+		}
+		c.idempotencyKey = func() (string, error) {
+			return gen(), nil
+		}
+		return nil
+	}
+}
+
+// WithUserIDHasher is a client option that sets the User of requests that don't have one to the result of hasher,
+// called with the context of the request, so that end users are attributed in one place instead of on every request.
+// OpenAI recommends a stable hash of the user's id, such as an HMAC, rather than anything identifying them. An empty
+// result leaves User unset. An explicitly set User is never replaced. It applies to chat completions, completions,
+// embeddings and images.
+func WithUserIDHasher(hasher func(ctx context.Context) string) ClientOption {
+	return func(c *client) error {
+		c.userIDHasher = hasher
+		return nil
+	}
+}
+
+// WithCache is a client option that caches the responses of deterministic requests in cache, such as an LRUCache, and
+// returns the cached response when the same request is made again instead of sending it. Completions are cached when
+// their temperature is 0 or they have a seed, and chat completions when they have a seed. The cache key is a hash of
+// the request and of the organization and project it's sent for, so any change to them is a different request.
+// Empty responses, such as one WithRetryOnEmpty gave up on, aren't cached. Cached responses have no Header. Streams
+// aren't cached.
+func WithCache(cache Cache) ClientOption {
+	return func(c *client) error {
+		c.cache = cache
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify is a client option that disables verification of the server's TLS certificate, for sending
+// requests through a local debugging proxy with a self-signed certificate.
+//
+// WARNING: this makes the client accept any certificate, so anyone on the network path can intercept requests,
+// including the api key, and tamper with responses. Never use it in production. It has no effect when WithHTTPClient
+// is used, as that client's transport is used as is.
+func WithInsecureSkipVerify() ClientOption {
+	return func(c *client) error {
+		c.insecureSkipVerify = true
+		return nil
+	}
+}
+
+// WithRetryOnEmpty is a client option that sends a chat completion again, up to n times, when none of the choices
+// of the response have any content or tool calls. Between attempts the request's seed is incremented when it's set,
+// otherwise its temperature is raised by 0.1, so that the retry doesn't give the same result. The retries are
+// limited by WithRateLimit like any other request. The default is not to retry.
+func WithRetryOnEmpty(n int) ClientOption {
+	return func(c *client) error {
+		c.retryOnEmpty = n
 		return nil
 	}
 }
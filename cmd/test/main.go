@@ -68,8 +68,11 @@ func main() {
 		MaxTokens: gpt3.IntPtr(20),
 	}
 
-	err = client.CompletionStream(ctx, request, func(resp *gpt3.CompletionResponse) {
-		fmt.Println(resp.Choices[0].Text)
+	err = client.CompletionStream(ctx, request, func(resp *gpt3.CompletionResponse) error {
+		if text, ok := resp.FirstChoiceText(); ok {
+			fmt.Println(text)
+		}
+		return nil
 	})
 	if err != nil {
 		log.Fatalln(err)
@@ -0,0 +1,93 @@
+package gpt3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ContentFilterEngine is the engine that classifies text for ContentFilter
+const ContentFilterEngine = "content-filter-alpha"
+
+// contentFilterToxicThreshold is the log probability below which an unsafe label is considered uncertain, as
+// documented by OpenAI for the content filter
+const contentFilterToxicThreshold = -0.355
+
+// FilterLabel is the classification of text by ContentFilter
+type FilterLabel int
+
+const (
+	// FilterLabelSafe means the text is safe
+	FilterLabelSafe FilterLabel = iota
+	// FilterLabelSensitive means the text talks about a sensitive topic, such as politics, religion or race
+	FilterLabelSensitive
+	// FilterLabelUnsafe means the text contains profane, prejudiced or hateful language
+	FilterLabelUnsafe
+)
+
+// String returns the name of the label
+func (l FilterLabel) String() string {
+	switch l {
+	case FilterLabelSafe:
+		return "safe"
+	case FilterLabelSensitive:
+		return "sensitive"
+	case FilterLabelUnsafe:
+		return "unsafe"
+	default:
+		return fmt.Sprintf("FilterLabel(%d)", int(l))
+	}
+}
+
+func (c *client) ContentFilter(ctx context.Context, text string) (FilterLabel, error) {
+	rsp, err := c.CompletionWithEngine(ctx, ContentFilterEngine, CompletionRequest{
+		Prompt:      []string{"<|endoftext|>" + text + "\n--\nLabel:"},
+		MaxTokens:   IntPtr(1),
+		Temperature: Float32Ptr(0),
+		TopP:        Float32Ptr(0),
+		LogProbs:    IntPtr(10),
+	})
+	if err != nil {
+		return FilterLabelUnsafe, err
+	}
+	if len(rsp.Choices) == 0 {
+		return FilterLabelUnsafe, errors.New("content filter returned no choices")
+	}
+	return contentFilterLabel(rsp.Choices[0]), nil
+}
+
+// contentFilterLabel reads the label of a content filter choice the way OpenAI documents it. An unsafe label whose
+// log probability is below the toxic threshold falls back to the more likely of safe and sensitive, and anything
+// that isn't a label is treated as unsafe.
+func contentFilterLabel(choice CompletionResponseChoice) FilterLabel {
+	label := strings.TrimSpace(choice.Text)
+	if label == "2" && choice.LogProbs != nil && len(choice.LogProbs.TopLogprobs) > 0 {
+		logprobs := choice.LogProbs.TopLogprobs[0]
+		if logprob, ok := logprobs["2"]; ok && logprob < contentFilterToxicThreshold {
+			logprob0, ok0 := logprobs["0"]
+			logprob1, ok1 := logprobs["1"]
+			switch {
+			case ok0 && ok1:
+				if logprob0 >= logprob1 {
+					label = "0"
+				} else {
+					label = "1"
+				}
+			case ok0:
+				label = "0"
+			case ok1:
+				label = "1"
+			}
+		}
+	}
+
+	switch label {
+	case "0":
+		return FilterLabelSafe
+	case "1":
+		return FilterLabelSensitive
+	default:
+		return FilterLabelUnsafe
+	}
+}
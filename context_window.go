@@ -0,0 +1,94 @@
+package gpt3
+
+import "fmt"
+
+// contextWindows is the number of tokens models can handle for the prompt and the completion together
+var contextWindows = map[string]int{
+	GPT3Dot5Turbo:            4096,
+	GPT3Dot5Turbo0301:        4096,
+	"gpt-3.5-turbo-1106":     16385,
+	"gpt-3.5-turbo-0125":     16385,
+	"gpt-3.5-turbo-16k":      16384,
+	"gpt-35-turbo":           4096,
+	"gpt-35-turbo-16k":       16384,
+	"gpt-4":                  8192,
+	"gpt-4-32k":              32768,
+	"gpt-4-1106-preview":     128000,
+	"gpt-4-0125-preview":     128000,
+	"gpt-4-turbo":            128000,
+	TextDavinci003Engine:     4097,
+	TextDavinci002Engine:     4097,
+	TextDavinci001Engine:     2049,
+	TextCurie001Engine:       2049,
+	TextBabbage001Engine:     2049,
+	TextAda001Engine:         2049,
+	DavinciEngine:            2049,
+	DavinciInstructEngine:    2049,
+	CurieEngine:              2049,
+	BabbageEngine:            2049,
+	AdaEngine:                2049,
+	"code-davinci-002":       8001,
+	"code-cushman-001":       2048,
+	TextEmbeddingAda002:      8191,
+	"text-embedding-3-small": 8191,
+	"text-embedding-3-large": 8191,
+}
+
+// maxOutputTokens is the number of completion tokens of models that limit the completion to less than what's left
+// of their context window
+var maxOutputTokens = map[string]int{
+	"gpt-3.5-turbo-1106": 4096,
+	"gpt-3.5-turbo-0125": 4096,
+	"gpt-4-1106-preview": 4096,
+	"gpt-4-0125-preview": 4096,
+	"gpt-4-turbo":        4096,
+}
+
+// ModelContextWindow returns the number of tokens model can handle for the prompt and the completion together, and
+// whether it's known. Dated snapshots such as "gpt-4-0613" have the context window of their base model, but other
+// variants such as "gpt-4-vision-preview" are unknown unless they are in the table themselves.
+func ModelContextWindow(model string) (int, bool) {
+	return lookupModel(contextWindows, model)
+}
+
+// ModelMaxOutputTokens returns the most tokens model can complete regardless of the prompt, and whether model has
+// such a limit. Models without one can complete whatever is left of their context window.
+func ModelMaxOutputTokens(model string) (int, bool) {
+	return lookupModel(maxOutputTokens, model)
+}
+
+// MaxCompletionTokens returns how many tokens are left in the context window of model for the completion of prompt,
+// for use as the MaxTokens of a request, limited to ModelMaxOutputTokens. Dated snapshots such as "gpt-4-0613" have
+// the context window of their base model. An error is returned when the context window or tokenizer of model isn't
+// known, or when the prompt doesn't leave room for any completion tokens.
+func MaxCompletionTokens(model, prompt string) (int, error) {
+	window, ok := ModelContextWindow(model)
+	if !ok {
+		return 0, fmt.Errorf("no context window known for model %q", model)
+	}
+	promptTokens, err := CountTokens(model, prompt)
+	if err != nil {
+		return 0, err
+	}
+	remaining := window - promptTokens
+	if remaining <= 0 {
+		return 0, fmt.Errorf("prompt is %d tokens which doesn't fit the %d token context window of model %q",
+			promptTokens, window, model)
+	}
+	if max, ok := ModelMaxOutputTokens(model); ok && remaining > max {
+		remaining = max
+	}
+	return remaining, nil
+}
+
+// lookupModel returns the value of model in table, or of the model it is a dated snapshot of
+func lookupModel(table map[string]int, model string) (int, bool) {
+	if value, ok := table[model]; ok {
+		return value, true
+	}
+	if base, ok := snapshotBase(model); ok {
+		value, ok := table[base]
+		return value, ok
+	}
+	return 0, false
+}
@@ -0,0 +1,157 @@
+package gpt3
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrFileNotFound is returned by DeleteFile when the file doesn't exist, such as when it was already deleted. It can
+// be checked with errors.Is, and the APIError of the response can still be matched with errors.As.
+var ErrFileNotFound = errors.New("file not found")
+
+// ErrUnknownModel is returned, wrapped with the name of the model, for a request whose model isn't Valid, such as
+// one with a typo. It's checked before the request is sent.
+var ErrUnknownModel = errors.New("unknown model")
+
+// InvalidRequestError is returned when the request was malformed or missing parameters (status 400). It can be
+// matched with errors.As, as can the APIError it wraps.
+type InvalidRequestError struct {
+	APIError
+}
+
+func (e InvalidRequestError) Unwrap() error {
+	return e.APIError
+}
+
+// AuthenticationError is returned when the API key is invalid, expired or revoked (status 401). It can be
+// matched with errors.As, as can the APIError it wraps.
+type AuthenticationError struct {
+	APIError
+}
+
+func (e AuthenticationError) Unwrap() error {
+	return e.APIError
+}
+
+// PermissionError is returned when the API key doesn't have access to the requested resource (status 403).
+// It can be matched with errors.As, as can the APIError it wraps.
+type PermissionError struct {
+	APIError
+}
+
+func (e PermissionError) Unwrap() error {
+	return e.APIError
+}
+
+// ServerError is returned when the API failed to handle a request on its side (status 5xx). It can be matched
+// with errors.As, as can the APIError it wraps.
+type ServerError struct {
+	APIError
+}
+
+func (e ServerError) Unwrap() error {
+	return e.APIError
+}
+
+// RateLimitError is returned when a request was rate limited (status 429). It can be matched with errors.As,
+// as can the APIError it wraps.
+type RateLimitError struct {
+	APIError
+	// RetryAfter is how long the API asked to wait before retrying, from the Retry-After header. It is zero
+	// when the header wasn't sent.
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Unwrap() error {
+	return e.APIError
+}
+
+// FileInUseError is returned by DeleteFile when the file can't be deleted because it's still used, such as by a
+// running fine-tune. It can be matched with errors.As, as can the error it wraps, such as InvalidRequestError, and its
+// APIError.
+type FileInUseError struct {
+	APIError
+	// err is the error of the request, which is APIError when not set
+	err error
+}
+
+func (e FileInUseError) Unwrap() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.APIError
+}
+
+// fileNotFoundError is an error of the API that matches ErrFileNotFound
+type fileNotFoundError struct {
+	APIError
+	err error
+}
+
+func (e fileNotFoundError) Unwrap() error {
+	return e.err
+}
+
+func (e fileNotFoundError) Is(target error) bool {
+	return target == ErrFileNotFound
+}
+
+// fileInUseCode is the code of the APIError returned when deleting a file that is still in use
+const fileInUseCode = "file_in_use"
+
+// newDeleteFileError returns the error of DeleteFile for err, telling apart files that don't exist and files that
+// are still in use from other failures. The returned errors wrap err, so its type can still be matched.
+func newDeleteFileError(err error) error {
+	var apiErr APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusNotFound:
+		return fileNotFoundError{APIError: apiErr, err: err}
+	case apiErr.StatusCode == http.StatusConflict, apiErr.Code == fileInUseCode:
+		return FileInUseError{APIError: apiErr, err: err}
+	}
+	return err
+}
+
+// newStatusError wraps apiErr in the error type matching the status code of resp, if there is one.
+func newStatusError(apiErr APIError, resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		return InvalidRequestError{apiErr}
+	case resp.StatusCode == http.StatusUnauthorized:
+		return AuthenticationError{apiErr}
+	case resp.StatusCode == http.StatusForbidden:
+		return PermissionError{apiErr}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return RateLimitError{
+			APIError:   apiErr,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	case resp.StatusCode >= 500:
+		return ServerError{apiErr}
+	}
+	return apiErr
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
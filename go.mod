@@ -3,9 +3,11 @@ module github.com/teamjobot/go-gpt3
 go 1.18
 
 require (
+	github.com/dlclark/regexp2 v1.10.0
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.2.3
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/net v0.0.0-20190628185345-da137c7871d7
+	golang.org/x/time v0.3.0
 )
 
 require (
@@ -1,16 +1,25 @@
 package gpt3
 
 import (
-	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 // Engine Types
@@ -51,6 +60,9 @@ const (
 	CodeSearchBabbageCode001  = "code-search-babbage-code-001"
 	CodeSearchBabbageText001  = "code-search-babbage-text-001"
 	TextEmbeddingAda002       = "text-embedding-ada-002"
+	TextModerationLatest      = "text-moderation-latest"
+	TextModerationStable      = "text-moderation-stable"
+	Whisper1                  = "whisper-1"
 )
 
 const (
@@ -63,53 +75,156 @@ var (
 	newLineRe = regexp.MustCompile(`\r?\n`)
 )
 
-func getEngineURL(engine string) string {
-	return fmt.Sprintf("%s/engines/%s/completions", defaultBaseURL, engine)
+// isChatModel returns true for models that are only available through the chat completions API
+func isChatModel(model string) bool {
+	for _, prefix := range []string{"gpt-3.5-turbo", "gpt-35-turbo", "gpt-4"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // A Client is an API client to communicate with the OpenAI gpt-3 APIs
 type Client interface {
 	// Engines lists the currently available engines, and provides basic information about each
 	// option such as the owner and availability.
+	//
+	// Deprecated: OpenAI has deprecated the engines endpoints, use Models instead.
 	Engines(ctx context.Context) (*EnginesResponse, error)
 
 	// Engine retrieves an engine instance, providing basic information about the engine such
 	// as the owner and availability.
+	//
+	// Deprecated: OpenAI has deprecated the engines endpoints, use Model instead.
 	Engine(ctx context.Context, engine string) (*EngineObject, error)
 
+	// Models lists the currently available models, and provides basic information about each one
+	// such as the owner and availability.
+	Models(ctx context.Context) (*ModelsResponse, error)
+
+	// Ping checks that the API can be reached and the api key is accepted without using any tokens, for example as
+	// a readiness check. It returns an AuthenticationError when the key is rejected.
+	Ping(ctx context.Context) error
+
+	// Model retrieves a model instance, providing basic information about the model such as the
+	// owner and permissioning.
+	Model(ctx context.Context, id string) (*ModelObject, error)
+
+	// DeleteModel deletes a fine-tuned model. You must have the Owner role in your organization.
+	DeleteModel(ctx context.Context, id string) (*DeleteModelResponse, error)
+
 	// ChatCompletion creates a completion with the Chat completion endpoint which
 	// is what powers the ChatGPT experience.
 	ChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error)
 
-	// ChatCompletion creates a completion with the Chat completion endpoint which
-	// is what powers the ChatGPT experience.
+	// ChatCompletionRaw is the same as ChatCompletion, but also returns the undecoded JSON response so that fields
+	// which aren't modelled by ChatCompletionResponse can be read.
+	ChatCompletionRaw(ctx context.Context, request ChatCompletionRequest) (json.RawMessage, *ChatCompletionResponse, error)
+
+	// ChatCompletionStream creates a completion with the Chat completion endpoint and streams the results
+	// through multiple calls to onData. Each chunk carries a Delta rather than a full message, and the tool calls
+	// of the deltas can be reassembled with a ToolCallAccumulator. Use ChatCompletionStreamWithUsage to get the
+	// token usage of the stream.
 	ChatCompletionStream(ctx context.Context, request ChatCompletionRequest, onData func(*ChatCompletionStreamResponse)) error
 
+	// ChatCompletionStreamWithUsage is the same as ChatCompletionStream, but also returns the token usage of the whole
+	// stream when request.StreamOptions.IncludeUsage is set, otherwise the returned usage is nil. The last chunk of
+	// such a stream only holds the usage and has no choices, so it isn't passed to onData.
+	ChatCompletionStreamWithUsage(
+		ctx context.Context,
+		request ChatCompletionRequest,
+		onData func(*ChatCompletionStreamResponse)) (*Usage, error)
+
+	// ChatCompletionStreamCollect streams a chat completion like ChatCompletionStreamWithUsage, passing each piece of content
+	// of the first choice to onDelta as it arrives, and returns the whole response assembled from the chunks,
+	// including the tool calls of each choice. The usage of the response is only set when
+	// request.StreamOptions.IncludeUsage is set. A chunk with a choice index below 0 or not below request.N, which
+	// defaults to 1, stops the stream with an error.
+	ChatCompletionStreamCollect(
+		ctx context.Context,
+		request ChatCompletionRequest,
+		onDelta func(string)) (*ChatCompletionResponse, error)
+
 	// Completion creates a completion with the default engine. This is the main endpoint of the API
 	// which auto-completes based on the given prompt.
 	Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error)
 
+	// CompletionRaw is the same as Completion, but also returns the undecoded JSON response so that fields which
+	// aren't modelled by CompletionResponse can be read.
+	CompletionRaw(ctx context.Context, request CompletionRequest) (json.RawMessage, *CompletionResponse, error)
+
 	// CompletionStream creates a completion with the default engine and streams the results through
-	// multiple calls to onData.
-	CompletionStream(ctx context.Context, request CompletionRequest, onData func(*CompletionResponse)) error
+	// multiple calls to onData. Returning an error from onData stops the stream and returns that error. Chunks can
+	// have no choices, such as keep-alives, so use FirstChoiceText rather than indexing Choices.
+	//
+	// With several prompts, or N above 1, the chunks of the choices are interleaved. Each streamed choice keeps the
+	// Index it's sent with, which is 0 when the server leaves it out, and belongs to prompt Index / N, so chunks can be
+	// routed to the text of their prompt by Index.
+	CompletionStream(ctx context.Context, request CompletionRequest, onData func(*CompletionResponse) error) error
+
+	// CompletionStreamText is the same as CompletionStream except it also returns the text of the first choice
+	// streamed, the one with index 0, concatenated. Chunks without choices, such as heartbeats, are passed to onData
+	// as is. onData may be nil when only the text is needed. The text streamed so far is returned along with an error.
+	CompletionStreamText(
+		ctx context.Context,
+		request CompletionRequest,
+		onData func(*CompletionResponse) error) (string, error)
+
+	// CompletionStreamReader creates a completion with the default engine and returns a stream to receive the
+	// results from with CompletionStream.Recv, instead of passing them to a callback. The stream must be closed.
+	CompletionStreamReader(ctx context.Context, request CompletionRequest) (*CompletionStream, error)
+
+	// CompletionBatch runs many completions with the default engine concurrently, with at most concurrency requests
+	// in flight. The responses and errors are in the same order as requests, and one request failing doesn't stop the
+	// others. Requests that haven't started when ctx is done aren't sent and have ctx.Err() as their error.
+	CompletionBatch(ctx context.Context, requests []CompletionRequest, concurrency int) ([]*CompletionResponse, []error)
 
 	// CompletionWithEngine is the same as Completion except allows overriding the default engine on the client
 	CompletionWithEngine(ctx context.Context, engine string, request CompletionRequest) (*CompletionResponse, error)
 
+	// Insert fills in the text between prefix and suffix with the default engine, returning the inserted text as
+	// the text of each choice. The other settings are taken from request, whose Prompt and Suffix are replaced. An
+	// error is returned without sending the request when the engine doesn't support insertion.
+	Insert(ctx context.Context, prefix, suffix string, request CompletionRequest) (*CompletionResponse, error)
+
+	// InsertWithEngine is the same as Insert except allows overriding the default engine on the client
+	InsertWithEngine(
+		ctx context.Context,
+		engine, prefix, suffix string,
+		request CompletionRequest) (*CompletionResponse, error)
+
 	// CompletionStreamWithEngine is the same as CompletionStream except allows overriding the default engine on the client
-	CompletionStreamWithEngine(ctx context.Context, engine string, request CompletionRequest, onData func(*CompletionResponse)) error
+	CompletionStreamWithEngine(
+		ctx context.Context,
+		engine string,
+		request CompletionRequest,
+		onData func(*CompletionResponse) error) error
 
-	// Given a prompt and an instruction, the model will return an edited version of the prompt.
+	// Edits is given a prompt and an instruction, and the model will return an edited version of the prompt.
+	// The edits API doesn't support streaming, so unlike completions and chat there's no streamed version. Its
+	// response reports Usage and Created like the other completion APIs.
 	Edits(ctx context.Context, request EditsRequest) (*EditsResponse, error)
 
 	// InterviewQuestions is a specialized form of completion with a different engine and question generation in mind
-	// given a job title and/or description.
+	// given a job title and/or description. The model is settings.Engine, defaulting to InterviewDefaultEngine, and
+	// chat models such as GPT3Dot5Turbo are sent the prompt as a user message through the chat completions API.
 	InterviewQuestions(
 		ctx context.Context,
 		input InterviewInput,
 		settings *InterviewRequestSettings,
 		options *InterviewOptions) (*InterviewResponse, error)
 
+	// InterviewQuestionsStream is the streaming form of InterviewQuestions, calling onQuestion with each question as
+	// soon as it has been generated. Questions are passed in the order they are generated, so options.Shuffle is
+	// ignored.
+	InterviewQuestionsStream(
+		ctx context.Context,
+		input InterviewInput,
+		settings *InterviewRequestSettings,
+		options *InterviewOptions,
+		onQuestion func(InterviewQuestion)) error
+
 	// Search performs a semantic search over a list of documents with the default engine.
 	Search(ctx context.Context, request SearchRequest) (*SearchResponse, error)
 
@@ -118,6 +233,69 @@ type Client interface {
 
 	// Returns an embedding using the provided request.
 	Embeddings(ctx context.Context, request EmbeddingsRequest) (*EmbeddingsResponse, error)
+
+	// EmbeddingsBatch gets the embeddings of any number of inputs with model, splitting them into several requests
+	// when they exceed the input count or token limits of a single request. The embeddings are in the order of inputs
+	// and Usage is the total of all requests. An error is returned without sending any request when an input alone
+	// exceeds the token limit of model or its tokenizer isn't known.
+	EmbeddingsBatch(ctx context.Context, model string, inputs []string) (*EmbeddingsResponse, error)
+
+	// Moderations classifies whether the given inputs violate OpenAI's content policy.
+	Moderations(ctx context.Context, request ModerationRequest) (*ModerationResponse, error)
+
+	// ContentFilter classifies text as safe, sensitive or unsafe with the ContentFilterEngine, rechecking uncertain
+	// unsafe labels with their log probabilities as OpenAI documents. The label is FilterLabelUnsafe when err is not
+	// nil. Moderations is the replacement for the content filter on newer accounts.
+	ContentFilter(ctx context.Context, text string) (FilterLabel, error)
+
+	// CreateImage creates images (DALL-E) given a prompt.
+	CreateImage(ctx context.Context, request ImageRequest) (*ImageResponse, error)
+
+	// CreateImageEdit creates edited or extended images given an original image and a prompt.
+	CreateImageEdit(ctx context.Context, request ImageEditRequest) (*ImageResponse, error)
+
+	// CreateImageVariation creates variations of a given image.
+	CreateImageVariation(ctx context.Context, request ImageVariationRequest) (*ImageResponse, error)
+
+	// CreateTranscription transcribes audio into the input language.
+	CreateTranscription(ctx context.Context, request AudioRequest) (*AudioResponse, error)
+
+	// CreateTranslation translates audio into English.
+	CreateTranslation(ctx context.Context, request AudioRequest) (*AudioResponse, error)
+
+	// UploadFile uploads a file that contains document(s) to be used across various endpoints/features.
+	UploadFile(ctx context.Context, request FileRequest) (*FileObject, error)
+
+	// ListFiles returns a list of files that belong to the user's organization.
+	ListFiles(ctx context.Context) (*FilesResponse, error)
+
+	// RetrieveFile returns information about a specific file.
+	RetrieveFile(ctx context.Context, id string) (*FileObject, error)
+
+	// DeleteFile deletes a file. Deleting a file that doesn't exist returns an error matching ErrFileNotFound, so
+	// cleanup can be repeated safely, and deleting a file still used by a fine-tune returns a FileInUseError.
+	DeleteFile(ctx context.Context, id string) (*DeleteFileResponse, error)
+
+	// CreateFineTune creates a job that fine-tunes a specified model from a given dataset.
+	CreateFineTune(ctx context.Context, request FineTuneRequest) (*FineTune, error)
+
+	// ListFineTunes lists your organization's fine-tuning jobs.
+	ListFineTunes(ctx context.Context) (*FineTunesResponse, error)
+
+	// RetrieveFineTune gets info about a fine-tune job.
+	RetrieveFineTune(ctx context.Context, id string) (*FineTune, error)
+
+	// CancelFineTune immediately cancels a fine-tune job.
+	CancelFineTune(ctx context.Context, id string) (*FineTune, error)
+
+	// ListFineTuneEvents calls onEvent with each of the status updates of a fine-tune job. When stream is true
+	// the events are streamed as they occur until the job finishes.
+	ListFineTuneEvents(ctx context.Context, id string, stream bool, onEvent func(*FineTuneEvent)) error
+
+	// Close closes the idle keep-alive connections of the client. Requests can still be made afterwards, which open
+	// new connections. It can be called any number of times, and does nothing when WithHTTPClient is used, as that
+	// client belongs to the caller. The error is always nil.
+	Close() error
 }
 
 type client struct {
@@ -125,30 +303,126 @@ type client struct {
 	apiKey        string
 	userAgent     string
 	httpClient    *http.Client
+	streamClient  *http.Client
+	timeout       time.Duration
 	defaultEngine string
+	defaultModel  string
 	idOrg         string
+	idProject     string
+	maxRetries    int
+	backoff       func(attempt int) time.Duration
+	azure         *azureConfig
+	headers       http.Header
+	logger        func(req *http.Request, resp *http.Response, body []byte)
+	observer      func(endpoint string, status int, dur time.Duration)
+	limiter       *rate.Limiter
+	// idempotencyKey generates the Idempotency-Key header of POST requests when set
+	idempotencyKey     func() (string, error)
+	insecureSkipVerify bool
+	transportTuning    *transportTuning
+	retryOnEmpty       int
+	userIDHasher       func(ctx context.Context) string
+	cache              Cache
+	// modelValidation is set by WithModelValidation, otherwise models are only validated for the OpenAI API
+	modelValidation *bool
+	// ownsHTTPClient is set when the http clients were created by NewClient rather than passed with WithHTTPClient
+	ownsHTTPClient bool
+}
+
+// endpointContextKey is the request context key of the API path a request was made to, without any query
+type endpointContextKey struct{}
+
+// orgContextKey is the context key of the organization set by WithRequestOrg
+type orgContextKey struct{}
+
+// WithRequestOrg returns a copy of ctx that sends requests made with it on behalf of the organization org, overriding
+// the organization of WithOrg. This lets a single client, and its connection pool, be shared by several
+// organizations.
+func WithRequestOrg(ctx context.Context, org string) context.Context {
+	return context.WithValue(ctx, orgContextKey{}, org)
 }
 
 // NewClient returns a new OpenAI GPT-3 API client. An apiKey is required to use the client
 func NewClient(apiKey string, options ...ClientOption) Client {
-	httpClient := &http.Client{
-		Timeout: time.Duration(defaultTimeoutSeconds * time.Second),
-	}
-
 	c := &client{
 		userAgent:     defaultUserAgent,
 		apiKey:        apiKey,
 		baseURL:       defaultBaseURL,
-		httpClient:    httpClient,
+		timeout:       time.Duration(defaultTimeoutSeconds * time.Second),
 		defaultEngine: DefaultEngine,
+		defaultModel:  GPT3Dot5Turbo,
 		idOrg:         "",
+		backoff:       DefaultBackoff,
 	}
 	for _, o := range options {
 		o(c)
 	}
+	if c.httpClient == nil {
+		// requests are limited by their context, which gets the client timeout in doRequest when it has no deadline
+		// of its own. Streams can run for much longer than a single request, so for them the timeout only covers
+		// connecting and waiting for the response headers rather than the whole stream.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{
+			Timeout:   c.timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+		transport.TLSHandshakeTimeout = c.timeout
+		if c.insecureSkipVerify {
+			tlsConfig := &tls.Config{}
+			if transport.TLSClientConfig != nil {
+				tlsConfig = transport.TLSClientConfig.Clone()
+			}
+			tlsConfig.InsecureSkipVerify = true
+			transport.TLSClientConfig = tlsConfig
+		}
+		if t := c.transportTuning; t != nil {
+			transport.MaxIdleConns = t.maxIdleConns
+			transport.MaxIdleConnsPerHost = t.maxIdleConnsPerHost
+			transport.IdleConnTimeout = t.idleTimeout
+		}
+		streamTransport := transport.Clone()
+		streamTransport.ResponseHeaderTimeout = c.timeout
+		c.httpClient = &http.Client{
+			Transport: transport,
+		}
+		c.streamClient = &http.Client{
+			Transport: streamTransport,
+		}
+		c.ownsHTTPClient = true
+	} else {
+		// the timeout of the supplied http client is used instead
+		c.timeout = 0
+		c.streamClient = c.httpClient
+	}
 	return c
 }
 
+func (c *client) Close() error {
+	if c.ownsHTTPClient {
+		c.httpClient.CloseIdleConnections()
+		c.streamClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+// requestUser returns the user to send with a request, which is user when it's set and otherwise the user of
+// WithUserIDHasher, if any
+func (c *client) requestUser(ctx context.Context, user string) string {
+	if user != "" || c.userIDHasher == nil {
+		return user
+	}
+	return c.userIDHasher(ctx)
+}
+
+// requestOrg returns the organization to send a request made with ctx on behalf of, which is the organization of
+// WithRequestOrg when it's set and otherwise the organization of WithOrg
+func (c *client) requestOrg(ctx context.Context) string {
+	if org, ok := ctx.Value(orgContextKey{}).(string); ok && len(org) > 0 {
+		return org
+	}
+	return c.idOrg
+}
+
 func (c *client) Engines(ctx context.Context) (*EnginesResponse, error) {
 	req, err := c.newRequest(ctx, "GET", "/engines", nil)
 	if err != nil {
@@ -183,86 +457,59 @@ func (c *client) Engine(ctx context.Context, engine string) (*EngineObject, erro
 	return output, nil
 }
 
-func (c *client) ChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
-	if request.Model == "" {
-		request.Model = GPT3Dot5Turbo
-	}
-	request.Stream = false
-
-	req, err := c.newRequest(ctx, "POST", "/chat/completions", request)
+func (c *client) Models(ctx context.Context) (*ModelsResponse, error) {
+	req, err := c.newRequest(ctx, "GET", "/models", nil)
 	if err != nil {
 		return nil, err
 	}
-
 	resp, err := c.performRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
-	output := new(ChatCompletionResponse)
+	output := new(ModelsResponse)
 	if err := getResponseObject(resp, output); err != nil {
 		return nil, err
 	}
 	return output, nil
 }
 
-func (c *client) ChatCompletionStream(
-	ctx context.Context,
-	request ChatCompletionRequest,
-	onData func(*ChatCompletionStreamResponse)) error {
-	if request.Model == "" {
-		request.Model = GPT3Dot5Turbo
-	}
-	request.Stream = true
-
-	req, err := c.newRequest(ctx, "POST", "/chat/completions", request)
+func (c *client) Ping(ctx context.Context) error {
+	req, err := c.newRequest(ctx, "GET", "/models?limit=1", nil)
 	if err != nil {
 		return err
 	}
-
 	resp, err := c.performRequest(req)
 	if err != nil {
 		return err
 	}
-
-	reader := bufio.NewReader(resp.Body)
+	// only the status matters, but the body is read so the connection can be reused
 	defer resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
 
-	for {
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			return err
-		}
-
-		// make sure there isn't any extra whitespace before or after
-		line = bytes.TrimSpace(line)
-		// the completion API only returns data events
-		if !bytes.HasPrefix(line, dataPrefix) {
-			continue
-		}
-		line = bytes.TrimPrefix(line, dataPrefix)
-
-		// the stream is completed when terminated by [DONE]
-		if bytes.HasPrefix(line, doneSequence) {
-			break
-		}
-		output := new(ChatCompletionStreamResponse)
-		if err := json.Unmarshal(line, output); err != nil {
-			return fmt.Errorf("invalid json stream data: %v", err)
-		}
-		onData(output)
+func (c *client) Model(ctx context.Context, id string) (*ModelObject, error) {
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/models/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
-}
-
-func (c *client) Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
-	return c.CompletionWithEngine(ctx, c.defaultEngine, request)
+	output := new(ModelObject)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
 }
 
-func (c *client) CompletionWithEngine(ctx context.Context, engine string, request CompletionRequest) (*CompletionResponse, error) {
-	request.Stream = false
-	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/engines/%s/completions", engine), request)
+// DeleteModel deletes a fine-tuned model. An APIError with a 404 StatusCode is returned when the model doesn't
+// exist or isn't owned by your organization.
+func (c *client) DeleteModel(ctx context.Context, id string) (*DeleteModelResponse, error) {
+	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/models/%s", id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -271,193 +518,1317 @@ func (c *client) CompletionWithEngine(ctx context.Context, engine string, reques
 		return nil, err
 	}
 
-	output := new(CompletionResponse)
+	output := new(DeleteModelResponse)
 	if err := getResponseObject(resp, output); err != nil {
 		return nil, err
 	}
 	return output, nil
 }
 
-func (c *client) CompletionStream(ctx context.Context, request CompletionRequest, onData func(*CompletionResponse)) error {
-	return c.CompletionStreamWithEngine(ctx, c.defaultEngine, request, onData)
+func (c *client) ChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	_, output, err := c.ChatCompletionRaw(ctx, request)
+	return output, err
 }
 
-var (
-	dataPrefix   = []byte("data: ")
-	doneSequence = []byte("[DONE]")
-)
-
-func (c *client) CompletionStreamWithEngine(
+func (c *client) ChatCompletionRaw(
 	ctx context.Context,
-	engine string,
-	request CompletionRequest,
-	onData func(*CompletionResponse),
-) error {
-	request.Stream = true
-	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/engines/%s/completions", engine), request)
-	if err != nil {
-		return err
+	request ChatCompletionRequest) (json.RawMessage, *ChatCompletionResponse, error) {
+	if request.Model == "" {
+		request.Model = c.defaultModel
 	}
-	resp, err := c.performRequest(req)
-	if err != nil {
-		return err
+	if err := c.checkModel(request.Model); err != nil {
+		return nil, nil, err
+	}
+	if err := validateResponseFormat(request); err != nil {
+		return nil, nil, err
 	}
+	request.Stream = false
+	request.StreamOptions = nil
+	request.User = c.requestUser(ctx, request.User)
 
-	reader := bufio.NewReader(resp.Body)
-	defer resp.Body.Close()
+	var key string
+	if c.cache != nil && isCacheableChatCompletion(request) {
+		key = c.cacheKey(ctx, "/chat/completions", request)
+		output := new(ChatCompletionResponse)
+		if raw, ok := c.cachedResponse(key, output); ok {
+			return raw, output, nil
+		}
+	}
 
-	for {
-		line, err := reader.ReadBytes('\n')
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, "POST", "/chat/completions", request)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		// make sure there isn't any extra whitespace before or after
-		line = bytes.TrimSpace(line)
-		// the completion API only returns data events
-		if !bytes.HasPrefix(line, dataPrefix) {
-			continue
+
+		resp, err := c.performRequest(req)
+		if err != nil {
+			return nil, nil, err
 		}
-		line = bytes.TrimPrefix(line, dataPrefix)
 
-		// the stream is completed when terminated by [DONE]
-		if bytes.HasPrefix(line, doneSequence) {
-			break
+		output := new(ChatCompletionResponse)
+		raw, err := getRawResponseObject(resp, output)
+		if err != nil {
+			return nil, nil, err
 		}
-		output := new(CompletionResponse)
-		if err := json.Unmarshal(line, output); err != nil {
-			return fmt.Errorf("invalid json stream data: %v", err)
+		if empty := isEmptyChatCompletion(output); attempt >= c.retryOnEmpty || !empty {
+			// an empty response, such as one that WithRetryOnEmpty gave up on, isn't cached so it can be retried
+			if !empty {
+				c.cacheResponse(key, raw)
+			}
+			return raw, output, nil
 		}
-		onData(output)
+		request = varyChatCompletionRequest(request)
 	}
-
-	return nil
 }
 
-func (c *client) Edits(ctx context.Context, request EditsRequest) (*EditsResponse, error) {
-	req, err := c.newRequest(ctx, "POST", "/edits", request)
-	if err != nil {
-		return nil, err
+// isEmptyChatCompletion reports whether none of the choices of a chat completion have any content or tool calls
+func isEmptyChatCompletion(output *ChatCompletionResponse) bool {
+	for _, choice := range output.Choices {
+		if choice.Message.Content != "" || len(choice.Message.ToolCalls) > 0 {
+			return false
+		}
 	}
-	resp, err := c.performRequest(req)
-	if err != nil {
-		return nil, err
+	return true
+}
+
+// isEmptyCompletion reports whether none of the choices of a completion have any text
+func isEmptyCompletion(output *CompletionResponse) bool {
+	for _, choice := range output.Choices {
+		if choice.Text != "" {
+			return false
+		}
 	}
+	return true
+}
 
-	output := new(EditsResponse)
-	if err := getResponseObject(resp, output); err != nil {
-		return nil, err
+// varyChatCompletionRequest returns request changed slightly so that retrying it doesn't give the same result. The
+// seed is incremented when it's set, otherwise the temperature is raised a little.
+func varyChatCompletionRequest(request ChatCompletionRequest) ChatCompletionRequest {
+	if request.Seed != nil {
+		request.Seed = IntPtr(*request.Seed + 1)
+		return request
 	}
-	return output, nil
+	// an unset temperature uses the API default of 1
+	temperature := request.Temperature
+	if temperature == 0 {
+		temperature = 1
+	}
+	request.Temperature = float32(math.Min(float64(temperature)+0.1, 2))
+	return request
 }
 
-func (c *client) Search(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
-	return c.SearchWithEngine(ctx, c.defaultEngine, request)
+func (c *client) ChatCompletionStream(
+	ctx context.Context,
+	request ChatCompletionRequest,
+	onData func(*ChatCompletionStreamResponse)) error {
+	_, err := c.ChatCompletionStreamWithUsage(ctx, request, onData)
+	return err
 }
 
-func (c *client) SearchWithEngine(ctx context.Context, engine string, request SearchRequest) (*SearchResponse, error) {
-	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/engines/%s/search", engine), request)
-	if err != nil {
-		return nil, err
+func (c *client) ChatCompletionStreamWithUsage(
+	ctx context.Context,
+	request ChatCompletionRequest,
+	onData func(*ChatCompletionStreamResponse)) (*Usage, error) {
+	if request.Model == "" {
+		request.Model = c.defaultModel
 	}
-	resp, err := c.performRequest(req)
-	if err != nil {
+	if err := c.checkModel(request.Model); err != nil {
 		return nil, err
 	}
-	output := new(SearchResponse)
-	if err := getResponseObject(resp, output); err != nil {
+	if err := validateResponseFormat(request); err != nil {
 		return nil, err
 	}
-	return output, nil
-}
+	request.Stream = true
+	request.User = c.requestUser(ctx, request.User)
 
-// Embeddings creates text embeddings for a supplied slice of inputs with a provided model.
-//
-// See: https://beta.openai.com/docs/api-reference/embeddings
-func (c *client) Embeddings(ctx context.Context, request EmbeddingsRequest) (*EmbeddingsResponse, error) {
-	req, err := c.newRequest(ctx, "POST", "/embeddings", request)
+	req, err := c.newRequest(ctx, "POST", "/chat/completions", request)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.performRequest(req)
+
+	resp, err := c.performStreamRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
-	output := EmbeddingsResponse{}
-	if err := getResponseObject(resp, &output); err != nil {
+	var usage *Usage
+	err = readStream(ctx, resp.Body, func(data []byte) error {
+		output := new(ChatCompletionStreamResponse)
+		if err := json.Unmarshal(data, output); err != nil {
+			return fmt.Errorf("invalid json stream data: %v", err)
+		}
+		if output.Usage != nil {
+			usage = output.Usage
+			if len(output.Choices) == 0 {
+				return nil
+			}
+		}
+		onData(output)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	return &output, nil
+	return usage, nil
 }
 
-func (c *client) performRequest(req *http.Request) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+func (c *client) ChatCompletionStreamCollect(
+	ctx context.Context,
+	request ChatCompletionRequest,
+	onDelta func(string)) (*ChatCompletionResponse, error) {
+	output := &ChatCompletionResponse{Object: "chat.completion"}
+	var (
+		contents  []*strings.Builder
+		toolCalls []*ToolCallAccumulator
+		// indexErr is the error of a chunk with a choice index the request can't have, which stops the stream
+		indexErr error
+	)
+	choices := request.N
+	if choices < 1 {
+		choices = 1
 	}
-	if err := checkForSuccess(resp); err != nil {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	usage, err := c.ChatCompletionStreamWithUsage(ctx, request, func(chunk *ChatCompletionStreamResponse) {
+		if indexErr != nil {
+			return
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index < 0 || choice.Index >= choices {
+				indexErr = fmt.Errorf("choice index %d is out of range for %d choices", choice.Index, choices)
+				cancel()
+				return
+			}
+		}
+		output.ID = chunk.ID
+		output.Created = chunk.Created
+		output.Model = chunk.Model
+		if chunk.SystemFingerprint != "" {
+			output.SystemFingerprint = chunk.SystemFingerprint
+		}
+		for _, choice := range chunk.Choices {
+			for len(output.Choices) <= choice.Index {
+				output.Choices = append(output.Choices, ChatCompletionResponseChoice{Index: len(output.Choices)})
+				contents = append(contents, new(strings.Builder))
+				toolCalls = append(toolCalls, new(ToolCallAccumulator))
+			}
+			collected := &output.Choices[choice.Index]
+			if choice.Delta.Role != "" {
+				collected.Message.Role = choice.Delta.Role
+			}
+			if choice.FinishReason != "" {
+				collected.FinishReason = choice.FinishReason
+			}
+			if choice.Logprobs != nil {
+				if collected.Logprobs == nil {
+					collected.Logprobs = new(ChatLogprobs)
+				}
+				collected.Logprobs.Content = append(collected.Logprobs.Content, choice.Logprobs.Content...)
+			}
+			contents[choice.Index].WriteString(choice.Delta.Content)
+			toolCalls[choice.Index].Add(choice.Delta)
+			if choice.Index == 0 && choice.Delta.Content != "" && onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	})
+	if indexErr != nil {
+		return nil, indexErr
+	}
+	if err != nil {
 		return nil, err
 	}
-	return resp, nil
-}
 
-// returns an error if this response includes an error.
-func checkForSuccess(resp *http.Response) error {
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return nil
+	for i := range output.Choices {
+		output.Choices[i].Message.Content = contents[i].String()
+		output.Choices[i].Message.ToolCalls = toolCalls[i].Finish()
 	}
-	defer resp.Body.Close()
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read from body: %w", err)
-	}
-	var result APIErrorResponse
-	if err := json.Unmarshal(data, &result); err != nil {
-		// if we can't decode the json error then create an unexpected error
-		apiError := APIError{
-			StatusCode: resp.StatusCode,
-			Type:       "Unexpected",
-			Message:    string(data),
-		}
-		return apiError
+	if usage != nil {
+		output.Usage = *usage
 	}
-	result.Error.StatusCode = resp.StatusCode
-	return result.Error
+	return output, nil
 }
 
-func getResponseObject(rsp *http.Response, v interface{}) error {
-	defer rsp.Body.Close()
-	if err := json.NewDecoder(rsp.Body).Decode(v); err != nil {
-		return fmt.Errorf("invalid json response: %w", err)
-	}
-	return nil
+func (c *client) Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
+	return c.CompletionWithEngine(ctx, c.defaultEngine, request)
 }
 
-func jsonBodyReader(body interface{}) (io.Reader, error) {
-	if body == nil {
-		return bytes.NewBuffer(nil), nil
-	}
-	raw, err := json.Marshal(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed encoding json: %w", err)
-	}
-	return bytes.NewBuffer(raw), nil
+func (c *client) CompletionWithEngine(ctx context.Context, engine string, request CompletionRequest) (*CompletionResponse, error) {
+	_, output, err := c.completionRaw(ctx, engine, request)
+	return output, err
 }
 
-func (c *client) newRequest(ctx context.Context, method, path string, payload interface{}) (*http.Request, error) {
-	bodyReader, err := jsonBodyReader(payload)
-	if err != nil {
+func (c *client) CompletionRaw(ctx context.Context, request CompletionRequest) (json.RawMessage, *CompletionResponse, error) {
+	return c.completionRaw(ctx, c.defaultEngine, request)
+}
+
+// insertionEngines are the engines that can complete text with a suffix
+var insertionEngines = map[string]bool{
+	TextDavinci002Engine: true,
+	TextDavinci003Engine: true,
+	"code-davinci-002":   true,
+}
+
+func (c *client) Insert(
+	ctx context.Context,
+	prefix, suffix string,
+	request CompletionRequest) (*CompletionResponse, error) {
+	return c.InsertWithEngine(ctx, c.defaultEngine, prefix, suffix, request)
+}
+
+func (c *client) InsertWithEngine(
+	ctx context.Context,
+	engine, prefix, suffix string,
+	request CompletionRequest) (*CompletionResponse, error) {
+	if !insertionEngines[engine] {
+		return nil, fmt.Errorf("engine %q doesn't support insertion", engine)
+	}
+	if suffix == "" {
+		return nil, errors.New("insertion requires a suffix")
+	}
+	request.Prompt = []string{prefix}
+	request.Suffix = suffix
+	return c.CompletionWithEngine(ctx, engine, request)
+}
+
+func (c *client) completionRaw(
+	ctx context.Context,
+	engine string,
+	request CompletionRequest) (json.RawMessage, *CompletionResponse, error) {
+	request.Stream = false
+	request.User = c.requestUser(ctx, request.User)
+	if err := request.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := c.checkModel(engine); err != nil {
+		return nil, nil, err
+	}
+	path := fmt.Sprintf("/engines/%s/completions", engine)
+
+	var key string
+	if c.cache != nil && isCacheableCompletion(request) {
+		key = c.cacheKey(ctx, path, request)
+		output := new(CompletionResponse)
+		if raw, ok := c.cachedResponse(key, output); ok {
+			if request.Echo {
+				markEcho(request, output.Choices, map[int]int{})
+			}
+			return raw, output, nil
+		}
+	}
+
+	req, err := c.newRequest(ctx, "POST", path, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output := new(CompletionResponse)
+	raw, err := getRawResponseObject(resp, output)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isEmptyCompletion(output) {
+		c.cacheResponse(key, raw)
+	}
+	if request.Echo {
+		markEcho(request, output.Choices, map[int]int{})
+	}
+	return raw, output, nil
+}
+
+func (c *client) CompletionStream(
+	ctx context.Context,
+	request CompletionRequest,
+	onData func(*CompletionResponse) error,
+) error {
+	return c.CompletionStreamWithEngine(ctx, c.defaultEngine, request, onData)
+}
+
+// doneSequence is the data of the event terminating a stream
+var doneSequence = []byte("[DONE]")
+
+func (c *client) CompletionStreamWithEngine(
+	ctx context.Context,
+	engine string,
+	request CompletionRequest,
+	onData func(*CompletionResponse) error,
+) error {
+	resp, err := c.startCompletionStream(ctx, engine, request)
+	if err != nil {
+		return err
+	}
+
+	decode := completionChunkDecoder(request)
+	return readStream(ctx, resp.Body, func(data []byte) error {
+		output, err := decode(data)
+		if err != nil {
+			return err
+		}
+		return onData(output)
+	})
+}
+
+func (c *client) CompletionStreamText(
+	ctx context.Context,
+	request CompletionRequest,
+	onData func(*CompletionResponse) error,
+) (string, error) {
+	var text strings.Builder
+	err := c.CompletionStream(ctx, request, func(rsp *CompletionResponse) error {
+		for _, choice := range rsp.Choices {
+			if choice.Index == 0 {
+				text.WriteString(choice.Text)
+			}
+		}
+		if onData == nil {
+			return nil
+		}
+		return onData(rsp)
+	})
+	return text.String(), err
+}
+
+// startCompletionStream sends a streamed completion request, returning the response whose body is the stream
+func (c *client) startCompletionStream(
+	ctx context.Context,
+	engine string,
+	request CompletionRequest) (*http.Response, error) {
+	request.Stream = true
+	request.User = c.requestUser(ctx, request.User)
+	if request.BestOf != nil && *request.BestOf > 1 {
+		return nil, errors.New("best_of can't be used when streaming completions")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.checkModel(engine); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/engines/%s/completions", engine), request)
+	if err != nil {
+		return nil, err
+	}
+	return c.performStreamRequest(req)
+}
+
+// completionChunkDecoder returns a function that decodes the chunks of a completion stream for request in order
+func completionChunkDecoder(request CompletionRequest) func(data []byte) (*CompletionResponse, error) {
+	// echoed tracks how many bytes of each choice's echoed prompt have been streamed so far
+	echoed := make(map[int]int)
+	return func(data []byte) (*CompletionResponse, error) {
+		output := new(CompletionResponse)
+		if err := json.Unmarshal(data, output); err != nil {
+			return nil, fmt.Errorf("invalid json stream data: %v", err)
+		}
+		if request.Echo {
+			markEcho(request, output.Choices, echoed)
+		}
+		for i := range output.Choices {
+			choice := &output.Choices[i]
+			if choice.FinishReason == FinishReasonStop {
+				choice.MatchedStop = matchStop(request.Stop, choice.StopReason)
+			}
+		}
+		return output, nil
+	}
+}
+
+// matchStop returns the stop sequence of the request that ended a choice that finished with FinishReasonStop. It's
+// the stop reason reported by the server when it's one of the sequences, otherwise the only sequence when the request
+// has just one, or "" when it can't be known.
+func matchStop(stop []string, stopReason interface{}) string {
+	if reason, ok := stopReason.(string); ok {
+		for _, sequence := range stop {
+			if sequence == reason {
+				return sequence
+			}
+		}
+	}
+	if len(stop) == 1 {
+		return stop[0]
+	}
+	return ""
+}
+
+// markEcho sets EchoLength on choices for the part of their text that is the echoed prompt. The prompt of a choice
+// is found from its index, since the API returns n choices for each prompt in order. echoed holds the number of
+// prompt bytes already seen per choice index, so a prompt split over several streamed chunks is only counted once.
+func markEcho(request CompletionRequest, choices []CompletionResponseChoice, echoed map[int]int) {
+	n := 1
+	if request.N != nil && *request.N > 1 {
+		n = *request.N
+	}
+	for i := range choices {
+		choice := &choices[i]
+		promptIndex := choice.Index / n
+		if promptIndex >= len(request.Prompt) {
+			continue
+		}
+		remaining := len(request.Prompt[promptIndex]) - echoed[choice.Index]
+		if remaining <= 0 {
+			continue
+		}
+		if remaining > len(choice.Text) {
+			remaining = len(choice.Text)
+		}
+		choice.EchoLength = remaining
+		echoed[choice.Index] += remaining
+	}
+}
+
+// readStream reads the server-sent events from body and passes the data of each event to onData until the
+// stream is terminated by [DONE], or the body ends after at least one event. An empty stream returns
+// io.ErrUnexpectedEOF. The body is always closed before returning. If ctx is done while waiting for data the body
+// is closed to unblock the read and the context error is returned.
+func readStream(ctx context.Context, body io.ReadCloser, onData func([]byte) error) error {
+	scanner := newSSEScanner(body)
+	defer body.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			body.Close()
+		case <-stop:
+		}
+	}()
+
+	received := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		event, err := scanner.Next()
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != io.EOF {
+				return err
+			}
+			// some proxies close the stream without sending [DONE], which is only a failure if nothing was received
+			if received {
+				return nil
+			}
+			return io.ErrUnexpectedEOF
+		}
+
+		data := bytes.TrimSpace(event.Data)
+		// the stream is completed when terminated by [DONE]
+		if bytes.Equal(data, doneSequence) {
+			return nil
+		}
+		if err := onData(data); err != nil {
+			return err
+		}
+		received = true
+	}
+}
+
+func (c *client) Edits(ctx context.Context, request EditsRequest) (*EditsResponse, error) {
+	req, err := c.newRequest(ctx, "POST", "/edits", request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(EditsResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) Search(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
+	return c.SearchWithEngine(ctx, c.defaultEngine, request)
+}
+
+func (c *client) SearchWithEngine(ctx context.Context, engine string, request SearchRequest) (*SearchResponse, error) {
+	if len(request.Documents) > 0 && request.File != "" {
+		return nil, errors.New("only one of documents or file can be searched")
+	}
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/engines/%s/search", engine), request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	output := new(SearchResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// Embeddings creates text embeddings for a supplied slice of inputs with a provided model.
+//
+// See: https://beta.openai.com/docs/api-reference/embeddings
+func (c *client) Embeddings(ctx context.Context, request EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	if err := c.checkModel(request.Model); err != nil {
+		return nil, err
+	}
+	request.User = c.requestUser(ctx, request.User)
+	req, err := c.newRequest(ctx, "POST", "/embeddings", request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := EmbeddingsResponse{}
+	if err := getResponseObject(resp, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// Moderations checks whether the supplied inputs violate OpenAI's usage policies.
+//
+// See: https://platform.openai.com/docs/api-reference/moderations
+func (c *client) Moderations(ctx context.Context, request ModerationRequest) (*ModerationResponse, error) {
+	req, err := c.newRequest(ctx, "POST", "/moderations", request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(ModerationResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// CreateImage generates images from a text prompt.
+//
+// See: https://platform.openai.com/docs/api-reference/images/create
+func (c *client) CreateImage(ctx context.Context, request ImageRequest) (*ImageResponse, error) {
+	if err := validateImageSize(request.Size); err != nil {
+		return nil, err
+	}
+	request.User = c.requestUser(ctx, request.User)
+	req, err := c.newRequest(ctx, "POST", "/images/generations", request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(ImageResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// CreateImageEdit generates edited images from an original image, an optional mask and a prompt.
+//
+// See: https://platform.openai.com/docs/api-reference/images/create-edit
+func (c *client) CreateImageEdit(ctx context.Context, request ImageEditRequest) (*ImageResponse, error) {
+	if request.Image == nil {
+		return nil, errors.New("an image is required")
+	}
+	if err := validateImageSize(request.Size); err != nil {
+		return nil, err
+	}
+	request.User = c.requestUser(ctx, request.User)
+	req, err := c.newMultipartRequest(ctx, "/images/edits", func(form *formWriter) {
+		form.file("image", "image.png", request.Image)
+		if request.Mask != nil {
+			form.file("mask", "mask.png", request.Mask)
+		}
+		form.field("prompt", request.Prompt)
+		form.intField("n", request.N)
+		form.field("size", request.Size)
+		form.field("response_format", request.ResponseFormat)
+		form.field("user", request.User)
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(ImageResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// CreateImageVariation generates variations of an image.
+//
+// See: https://platform.openai.com/docs/api-reference/images/create-variation
+func (c *client) CreateImageVariation(ctx context.Context, request ImageVariationRequest) (*ImageResponse, error) {
+	if request.Image == nil {
+		return nil, errors.New("an image is required")
+	}
+	if err := validateImageSize(request.Size); err != nil {
+		return nil, err
+	}
+	request.User = c.requestUser(ctx, request.User)
+	req, err := c.newMultipartRequest(ctx, "/images/variations", func(form *formWriter) {
+		form.file("image", "image.png", request.Image)
+		form.intField("n", request.N)
+		form.field("size", request.Size)
+		form.field("response_format", request.ResponseFormat)
+		form.field("user", request.User)
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(ImageResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// CreateTranscription transcribes an audio file into the language it is spoken in.
+//
+// See: https://platform.openai.com/docs/api-reference/audio/create
+func (c *client) CreateTranscription(ctx context.Context, request AudioRequest) (*AudioResponse, error) {
+	return c.createAudio(ctx, "/audio/transcriptions", request)
+}
+
+// CreateTranslation translates an audio file into English text. The Language field of the request is ignored
+// since the output is always English.
+//
+// See: https://platform.openai.com/docs/api-reference/audio/create
+func (c *client) CreateTranslation(ctx context.Context, request AudioRequest) (*AudioResponse, error) {
+	request.Language = ""
+	return c.createAudio(ctx, "/audio/translations", request)
+}
+
+func (c *client) createAudio(ctx context.Context, path string, request AudioRequest) (*AudioResponse, error) {
+	if request.File == nil || request.FileName == "" {
+		return nil, errors.New("an audio file and file name are required")
+	}
+	if request.Model == "" {
+		request.Model = Whisper1
+	}
+	req, err := c.newMultipartRequest(ctx, path, func(form *formWriter) {
+		form.file("file", request.FileName, request.File)
+		form.field("model", request.Model)
+		form.field("prompt", request.Prompt)
+		form.field("response_format", request.ResponseFormat)
+		form.float32Field("temperature", request.Temperature)
+		form.field("language", request.Language)
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(AudioResponse)
+	switch request.ResponseFormat {
+	case AudioResponseFormatText, AudioResponseFormatSRT, AudioResponseFormatVTT:
+		// these formats are returned as plain text rather than json
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from body: %w", err)
+		}
+		output.Text = string(data)
+		output.Header = resp.Header
+	default:
+		if err := getResponseObject(resp, output); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+// UploadFile uploads a file for use with features like fine-tuning.
+//
+// See: https://platform.openai.com/docs/api-reference/files/upload
+func (c *client) UploadFile(ctx context.Context, request FileRequest) (*FileObject, error) {
+	if request.File == nil || request.FileName == "" {
+		return nil, errors.New("a file and file name are required")
+	}
+	req, err := c.newMultipartRequest(ctx, "/files", func(form *formWriter) {
+		form.file("file", request.FileName, request.File)
+		form.field("purpose", request.Purpose)
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(FileObject)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) ListFiles(ctx context.Context) (*FilesResponse, error) {
+	req, err := c.newRequest(ctx, "GET", "/files", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(FilesResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) RetrieveFile(ctx context.Context, id string) (*FileObject, error) {
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/files/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(FileObject)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) DeleteFile(ctx context.Context, id string) (*DeleteFileResponse, error) {
+	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/files/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, newDeleteFileError(err)
+	}
+
+	output := new(DeleteFileResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// CreateFineTune starts a fine-tuning job. Poll RetrieveFineTune until its Status is succeeded to get the
+// FineTunedModel name.
+//
+// See: https://platform.openai.com/docs/api-reference/fine-tunes/create
+func (c *client) CreateFineTune(ctx context.Context, request FineTuneRequest) (*FineTune, error) {
+	req, err := c.newRequest(ctx, "POST", "/fine-tunes", request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(FineTune)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) ListFineTunes(ctx context.Context) (*FineTunesResponse, error) {
+	req, err := c.newRequest(ctx, "GET", "/fine-tunes", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(FineTunesResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) RetrieveFineTune(ctx context.Context, id string) (*FineTune, error) {
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/fine-tunes/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(FineTune)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) CancelFineTune(ctx context.Context, id string) (*FineTune, error) {
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/fine-tunes/%s/cancel", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output := new(FineTune)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// ListFineTuneEvents lists the events of a fine-tune job. When stream is true the connection is kept open and
+// events are passed to onEvent as they occur, until the job finishes or ctx is done.
+//
+// See: https://platform.openai.com/docs/api-reference/fine-tunes/events
+func (c *client) ListFineTuneEvents(
+	ctx context.Context,
+	id string,
+	stream bool,
+	onEvent func(*FineTuneEvent),
+) error {
+	path := fmt.Sprintf("/fine-tunes/%s/events", id)
+	if stream {
+		path += "?stream=true"
+	}
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if stream {
+		resp, err = c.performStreamRequest(req)
+	} else {
+		resp, err = c.performRequest(req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if stream {
+		return readStream(ctx, resp.Body, func(data []byte) error {
+			output := new(FineTuneEvent)
+			if err := json.Unmarshal(data, output); err != nil {
+				return fmt.Errorf("invalid json stream data: %v", err)
+			}
+			onEvent(output)
+			return nil
+		})
+	}
+
+	output := new(FineTuneEventsResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return err
+	}
+	for i := range output.Data {
+		onEvent(&output.Data[i])
+	}
+	return nil
+}
+
+// validatePenalties checks the presence and frequency penalties, when set, are within the range the API accepts
+func validatePenalties(presence, frequency *float32) error {
+	if presence != nil && (*presence < -2 || *presence > 2) {
+		return fmt.Errorf("presence_penalty must be between -2.0 and 2.0, got %v", *presence)
+	}
+	if frequency != nil && (*frequency < -2 || *frequency > 2) {
+		return fmt.Errorf("frequency_penalty must be between -2.0 and 2.0, got %v", *frequency)
+	}
+	return nil
+}
+
+// validateResponseFormat checks the API's requirement that JSON mode is only used when one of the messages
+// mentions JSON, as otherwise the model can generate whitespace until it runs out of tokens.
+func validateResponseFormat(request ChatCompletionRequest) error {
+	if request.ResponseFormat == nil || request.ResponseFormat.Type != ResponseFormatTypeJSONObject {
+		return nil
+	}
+	for _, message := range request.Messages {
+		if strings.Contains(strings.ToLower(message.Content), "json") {
+			return nil
+		}
+	}
+	return errors.New("json_object response format requires a message that mentions JSON")
+}
+
+func validateImageSize(size string) error {
+	switch size {
+	case "", ImageSize256x256, ImageSize512x512, ImageSize1024x1024:
+		return nil
+	}
+	return fmt.Errorf("invalid image size %q, must be one of %s, %s or %s",
+		size, ImageSize256x256, ImageSize512x512, ImageSize1024x1024)
+}
+
+func (c *client) performRequest(req *http.Request) (*http.Response, error) {
+	return c.doRequest(c.httpClient, req, false)
+}
+
+// performStreamRequest performs a request whose response body is streamed. Unlike performRequest the client
+// timeout doesn't limit how long the stream can be read for.
+func (c *client) performStreamRequest(req *http.Request) (*http.Response, error) {
+	return c.doRequest(c.streamClient, req, true)
+}
+
+func (c *client) doRequest(httpClient *http.Client, req *http.Request, stream bool) (*http.Response, error) {
+	if c.maxRetries > 0 || c.logger != nil {
+		if err := bufferBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForRateLimit(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := c.send(httpClient, req, stream)
+		if err == nil {
+			return resp, nil
+		}
+		if resp == nil || attempt >= c.maxRetries || !isRetryableStatus(resp.StatusCode) {
+			return nil, err
+		}
+
+		delay := c.backoff(attempt)
+		var rateLimitErr RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+			delay = rateLimitErr.RetryAfter
+		}
+		if delay > 0 {
+			if err := sleepContext(req.Context(), delay); err != nil {
+				return nil, err
+			}
+		} else if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		if err := rewindBody(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send sends a single attempt of req, returning an error for unsuccessful responses along with the response. A request
+// that isn't streamed is limited to the client timeout unless its context already has a deadline.
+func (c *client) send(httpClient *http.Client, req *http.Request, stream bool) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if _, ok := req.Context().Deadline(); !ok && !stream && c.timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), c.timeout)
+		req = req.WithContext(ctx)
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	c.observe(req, resp, time.Since(start))
+	if err == nil {
+		err = decodeResponseBody(resp)
+	}
+	if logErr := c.logExchange(req, resp, stream); logErr != nil {
+		cancel()
+		return nil, logErr
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := checkForSuccess(resp); err != nil {
+		cancel()
+		return resp, err
+	}
+	// the timeout covers reading the body, so it's only released once the body is closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody is a response body that cancels the context of its request when closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// gzipBody is a decompressed response body
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+// Close closes the compressed body. The gzip reader holds no resources, and isn't closed so that the body can be
+// closed while it's being read to interrupt a stream.
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
+
+// decodeResponseBody replaces a gzip encoded response body with its decompressed content
+func decodeResponseBody(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	switch {
+	case err == io.EOF:
+		// an empty body has nothing to decompress
+	case err != nil:
+		resp.Body.Close()
+		return fmt.Errorf("invalid gzip response: %v", err)
+	default:
+		resp.Body = &gzipBody{Reader: reader, body: resp.Body}
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// waitForRateLimit blocks until the client's rate limiter allows another request, or ctx is done
+func (c *client) waitForRateLimit(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	if err := c.limiter.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// the limiter fails early when ctx would reach its deadline before a request is allowed
+		if _, ok := ctx.Deadline(); ok {
+			return context.DeadlineExceeded
+		}
+		return err
+	}
+	return nil
+}
+
+// observe passes the outcome of a request to the client's observer. The status is 0 when the request failed.
+func (c *client) observe(req *http.Request, resp *http.Response, dur time.Duration) {
+	if c.observer == nil {
+		return
+	}
+	endpoint, _ := req.Context().Value(endpointContextKey{}).(string)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.observer(endpoint, status, dur)
+}
+
+// returns an error if this response includes an error. The error is an APIError, wrapped in one of the typed
+// errors from newStatusError for common status codes.
+func checkForSuccess(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read from body: %w", err)
+	}
+	var result APIErrorResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		// if we can't decode the json error then create an unexpected error
+		result.Error = APIError{
+			Type:    "Unexpected",
+			Message: string(data),
+		}
+	}
+	result.Error.StatusCode = resp.StatusCode
+	result.Error.RequestID = resp.Header.Get("x-request-id")
+	return newStatusError(result.Error, resp)
+}
+
+func getResponseObject(rsp *http.Response, v interface{}) error {
+	defer rsp.Body.Close()
+	if err := json.NewDecoder(rsp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid json response: %w", err)
+	}
+	setResponseHeaders(rsp, v)
+	return nil
+}
+
+// setResponseHeaders sets the headers of rsp on v when it embeds ResponseHeaders
+func setResponseHeaders(rsp *http.Response, v interface{}) {
+	if headers, ok := v.(interface{ setHeader(http.Header) }); ok {
+		headers.setHeader(rsp.Header)
+	}
+}
+
+// getRawResponseObject decodes the response into v like getResponseObject, and also returns the undecoded body
+func getRawResponseObject(rsp *http.Response, v interface{}) (json.RawMessage, error) {
+	defer rsp.Body.Close()
+	data, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from body: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("invalid json response: %w", err)
+	}
+	setResponseHeaders(rsp, v)
+	return json.RawMessage(data), nil
+}
+
+func jsonBodyReader(body interface{}) (io.Reader, error) {
+	if body == nil {
+		return bytes.NewBuffer(nil), nil
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding json: %w", err)
+	}
+	return bytes.NewBuffer(raw), nil
+}
+
+// formWriter writes multipart form fields, skipping empty values and keeping the first error that occurs
+// so callers don't have to check each write.
+type formWriter struct {
+	*multipart.Writer
+	err error
+}
+
+func (f *formWriter) field(name, value string) {
+	if f.err != nil || value == "" {
+		return
+	}
+	f.err = f.WriteField(name, value)
+}
+
+func (f *formWriter) intField(name string, value *int) {
+	if value != nil {
+		f.field(name, strconv.Itoa(*value))
+	}
+}
+
+func (f *formWriter) float32Field(name string, value *float32) {
+	if value != nil {
+		f.field(name, strconv.FormatFloat(float64(*value), 'f', -1, 32))
+	}
+}
+
+func (f *formWriter) file(name, filename string, r io.Reader) {
+	if f.err != nil {
+		return
+	}
+	part, err := f.CreateFormFile(name, filename)
+	if err != nil {
+		f.err = err
+		return
+	}
+	_, f.err = io.Copy(part, r)
+}
+
+func (c *client) newRequest(ctx context.Context, method, path string, payload interface{}) (*http.Request, error) {
+	bodyReader, err := jsonBodyReader(payload)
+	if err != nil {
 		return nil, err
 	}
+	return c.newRequestWithBody(ctx, method, path, bodyReader, "application/json")
+}
+
+// newMultipartRequest creates a multipart/form-data POST request whose form is populated by writeForm.
+func (c *client) newMultipartRequest(ctx context.Context, path string, writeForm func(*formWriter)) (*http.Request, error) {
+	body := new(bytes.Buffer)
+	form := &formWriter{Writer: multipart.NewWriter(body)}
+	writeForm(form)
+	if form.err != nil {
+		return nil, fmt.Errorf("failed encoding form: %w", form.err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed encoding form: %w", err)
+	}
+	return c.newRequestWithBody(ctx, "POST", path, body, form.FormDataContentType())
+}
+
+func (c *client) newRequestWithBody(
+	ctx context.Context,
+	method, path string,
+	body io.Reader,
+	contentType string) (*http.Request, error) {
 	url := c.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if c.azure != nil {
+		var err error
+		if url, err = c.azure.url(c.baseURL, path); err != nil {
+			return nil, err
+		}
+	}
+	endpoint := path
+	if i := strings.Index(endpoint, "?"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	ctx = context.WithValue(ctx, endpointContextKey{}, endpoint)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
-	if len(c.idOrg) > 0 {
-		req.Header.Set("OpenAI-Organization", c.idOrg)
+	if org := c.requestOrg(ctx); len(org) > 0 {
+		req.Header.Set("OpenAI-Organization", org)
+	}
+	if len(c.idProject) > 0 {
+		req.Header.Set("OpenAI-Project", c.idProject)
+	}
+	req.Header.Set("Content-type", contentType)
+	// compression is requested explicitly rather than left to the transport, so responses are compressed with any
+	// http client, and decodeResponseBody decompresses them
+	req.Header.Set("Accept-Encoding", "gzip")
+	if c.idempotencyKey != nil && method == http.MethodPost {
+		key, err := c.idempotencyKey()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if c.azure != nil {
+		req.Header.Set("api-key", c.apiKey)
+	} else {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	}
+	for key, values := range c.headers {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
 	}
-	req.Header.Set("Content-type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 	return req, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -40,6 +41,13 @@ const (
 	InterviewDefaultCap    = 5
 	InterviewDefaultEngine = "text-davinci-001"
 	InterviewMaxCap        = 50
+
+	// Defaults for InterviewRequestSettings fields that are nil, see Completion Request Settings comments at top of file
+	InterviewDefaultFrequencyPenalty float32 = .75
+	InterviewDefaultMaxTokens                = 175
+	InterviewDefaultPresencePenalty  float32 = .7
+	InterviewDefaultTemperature      float32 = 1
+	InterviewDefaultTopP             float32 = 0.85
 )
 
 type InterviewInput struct {
@@ -76,12 +84,15 @@ type InterviewRequest struct {
 
 // InterviewRequestSettings allows granular overrides of most AI settings.
 // Originally wasn't exposing any GPT settings to encapsulate and simplify caller use; later did for more control
-// but still not exposing some things that could conflict or confuse.
+// but still not exposing some things that could conflict or confuse. Sampling settings left nil use the matching
+// InterviewDefault value, so for example a lower MaxTokens trades question count for speed.
 type InterviewRequestSettings struct {
-	Engine           string   `json:"engine"`
-	FrequencyPenalty float32  `json:"frequencyPenalty"`
+	// Engine is the model used to generate questions, either a completions or chat model. Defaults to
+	// InterviewDefaultEngine when nil or empty.
+	Engine           *string  `json:"engine"`
+	FrequencyPenalty *float32 `json:"frequencyPenalty"`
 	MaxTokens        *int     `json:"maxTokens"`
-	PresencePenalty  float32  `json:"presencePenalty"`
+	PresencePenalty  *float32 `json:"presencePenalty"`
 	Temperature      *float32 `json:"temperature"`
 	TopP             *float32 `json:"topP"`
 	User             string   `json:"user"`
@@ -106,10 +117,10 @@ func (r *InterviewResponse) HasQuestions() bool {
 func (r *InterviewResponse) QuestionText() string {
 	var sb strings.Builder
 
-	for index, r := range r.Questions {
-		sb.WriteString(r.Question)
+	for index, q := range r.Questions {
+		sb.WriteString(q.Question)
 
-		if index+1 < len(r.Question) {
+		if index+1 < len(r.Questions) {
 			sb.WriteString("\n\n")
 		}
 	}
@@ -123,24 +134,19 @@ func formatInterviewInput(input string) string {
 	return output
 }
 
-func getInterviewPrompt(jobTitle, jobDesc string) string {
-	var prompt string
+// interviewPrompt is the prompt asking for interview questions for a job title, a job description or both
+var interviewPrompt = MustPromptTemplate("Create a list of questions for my interview with a " +
+	"{{if .title}}{{.title}}{{if .description}}, {{.description}}{{end}}{{else}}job description of {{.description}}{{end}}")
 
+func getInterviewPrompt(jobTitle, jobDesc string) string {
 	// TODO: if cap provided, consider "Create a list of %d questions" with cap
-	if len(jobTitle) > 0 && len(jobDesc) > 0 {
-		prompt = fmt.Sprintf(
-			"Create a list of questions for my interview with a %s, %s",
-			formatInterviewInput(jobTitle),
-			formatInterviewInput(jobDesc))
-	} else if len(jobTitle) > 0 {
-		prompt = fmt.Sprintf("Create a list of questions for my interview with a %s", formatInterviewInput(jobTitle))
-	} else if len(jobDesc) > 0 {
-		prompt = fmt.Sprintf(
-			"Create a list of questions for my interview with a job description of %s",
-			formatInterviewInput(jobDesc))
+	if len(jobTitle) == 0 && len(jobDesc) == 0 {
+		return ""
 	}
-
-	return prompt
+	return interviewPrompt.MustRender(map[string]string{
+		"title":       formatInterviewInput(jobTitle),
+		"description": formatInterviewInput(jobDesc),
+	})
 }
 
 func NewInterviewOptions(cap int) *InterviewOptions {
@@ -153,12 +159,12 @@ func NewInterviewOptions(cap int) *InterviewOptions {
 func NewInterviewSettings(user string) *InterviewRequestSettings {
 	// See Completion Request Settings comments at top of file
 	request := &InterviewRequestSettings{
-		Engine:           InterviewDefaultEngine,
-		FrequencyPenalty: .75,
-		MaxTokens:        IntPtr(175),
-		PresencePenalty:  .7,
-		Temperature:      Float32Ptr(1),
-		TopP:             Float32Ptr(0.85),
+		Engine:           StringPtr(InterviewDefaultEngine),
+		FrequencyPenalty: Float32Ptr(InterviewDefaultFrequencyPenalty),
+		MaxTokens:        IntPtr(InterviewDefaultMaxTokens),
+		PresencePenalty:  Float32Ptr(InterviewDefaultPresencePenalty),
+		Temperature:      Float32Ptr(InterviewDefaultTemperature),
+		TopP:             Float32Ptr(InterviewDefaultTopP),
 		User:             user,
 	}
 	return request
@@ -178,10 +184,10 @@ func NewInterviewSettingsRand(user string) *InterviewRequestSettings {
 	}
 
 	request := &InterviewRequestSettings{
-		Engine:           InterviewDefaultEngine,
-		FrequencyPenalty: float32Rand(0.2, 0.85),
+		Engine:           StringPtr(InterviewDefaultEngine),
+		FrequencyPenalty: float32PtrRand(0.2, 0.85),
 		MaxTokens:        intPtrRand(175, 275),
-		PresencePenalty:  float32Rand(0.1, 0.8),
+		PresencePenalty:  float32PtrRand(0.1, 0.8),
 		Temperature:      temp,
 		TopP:             topP,
 		User:             user,
@@ -190,53 +196,113 @@ func NewInterviewSettingsRand(user string) *InterviewRequestSettings {
 	return request
 }
 
+// engine returns the model of the settings, or InterviewDefaultEngine when it isn't set
+func (s *InterviewRequestSettings) engine() string {
+	if s.Engine == nil || *s.Engine == "" {
+		return InterviewDefaultEngine
+	}
+	return *s.Engine
+}
+
 func mapInterviewSettings(settings *InterviewRequestSettings, prompt string) CompletionRequest {
 	return CompletionRequest{
 		Echo:             false,
-		FrequencyPenalty: settings.FrequencyPenalty,
+		FrequencyPenalty: float32PtrDefault(settings.FrequencyPenalty, InterviewDefaultFrequencyPenalty),
 		LogProbs:         nil,
-		MaxTokens:        settings.MaxTokens,
+		MaxTokens:        intPtrDefault(settings.MaxTokens, InterviewDefaultMaxTokens),
 		N:                IntPtr(1),
-		PresencePenalty:  settings.PresencePenalty,
+		PresencePenalty:  float32PtrDefault(settings.PresencePenalty, InterviewDefaultPresencePenalty),
 		Prompt:           []string{prompt},
 		Stop:             nil,
 		Stream:           false,
-		Temperature:      settings.Temperature,
-		TopP:             settings.TopP,
+		Temperature:      float32PtrDefault(settings.Temperature, InterviewDefaultTemperature),
+		TopP:             float32PtrDefault(settings.TopP, InterviewDefaultTopP),
 		User:             settings.User,
 	}
 }
 
-func (c *client) InterviewQuestions(
+func mapInterviewChatSettings(settings *InterviewRequestSettings, prompt string) ChatCompletionRequest {
+	request := mapInterviewSettings(settings, prompt)
+	return ChatCompletionRequest{
+		FrequencyPenalty: *request.FrequencyPenalty,
+		MaxTokens:        *request.MaxTokens,
+		Messages:         []ChatCompletionRequestMessage{{Role: RoleUser, Content: prompt}},
+		Model:            settings.engine(),
+		N:                1,
+		PresencePenalty:  *request.PresencePenalty,
+		Temperature:      *request.Temperature,
+		TopP:             *request.TopP,
+		User:             settings.User,
+	}
+}
+
+// interviewChoices generates the questions with the engine of settings, using the chat completions API for chat models and
+// the completions API otherwise. Chat choices are returned as completion choices so both are parsed the same way.
+func (c *client) interviewChoices(
 	ctx context.Context,
+	settings *InterviewRequestSettings,
+	prompt string) ([]CompletionResponseChoice, error) {
+
+	if engine := settings.engine(); !isChatModel(engine) {
+		resp, err := c.CompletionWithEngine(ctx, engine, mapInterviewSettings(settings, prompt))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Choices, nil
+	}
+
+	resp, err := c.ChatCompletion(ctx, mapInterviewChatSettings(settings, prompt))
+	if err != nil {
+		return nil, err
+	}
+	choices := make([]CompletionResponseChoice, len(resp.Choices))
+	for i, ch := range resp.Choices {
+		choices[i] = CompletionResponseChoice{
+			Text:         ch.Message.Content,
+			Index:        ch.Index,
+			FinishReason: ch.FinishReason,
+		}
+	}
+	return choices, nil
+}
+
+// prepareInterview validates the arguments of an interview request, filling in defaults, and returns the prompt
+func prepareInterview(
 	input InterviewInput,
 	settings *InterviewRequestSettings,
-	options *InterviewOptions) (*InterviewResponse, error) {
+	options *InterviewOptions) (string, *InterviewOptions, error) {
 
-	start := time.Now()
 	jobTitle := trimStr(input.JobTitle)
 	jobDesc := trimStr(input.JobDescription)
 
 	if len(jobTitle) == 0 && len(jobDesc) == 0 {
-		return nil, errors.New("must specify a job title or description")
+		return "", nil, errors.New("must specify a job title or description")
 	}
 
 	if settings == nil {
-		return nil, errors.New("request settings are required")
-	}
-	if len(settings.Engine) == 0 {
-		settings.Engine = InterviewDefaultEngine
+		return "", nil, errors.New("request settings are required")
 	}
 	if options == nil {
 		options = NewInterviewOptions(InterviewDefaultCap)
 	}
 
-	engine := InterviewDefaultEngine
-	prompt := getInterviewPrompt(jobTitle, jobDesc)
-	request := mapInterviewSettings(settings, prompt)
+	return getInterviewPrompt(jobTitle, jobDesc), options, nil
+}
+
+func (c *client) InterviewQuestions(
+	ctx context.Context,
+	input InterviewInput,
+	settings *InterviewRequestSettings,
+	options *InterviewOptions) (*InterviewResponse, error) {
+
+	start := time.Now()
+	prompt, options, err := prepareInterview(input, settings, options)
+	if err != nil {
+		return nil, err
+	}
 	quesCap := options.GetCap()
 
-	resp, err := c.CompletionWithEngine(ctx, engine, request)
+	choices, err := c.interviewChoices(ctx, settings, prompt)
 
 	if err != nil {
 		return nil, err
@@ -250,28 +316,115 @@ func (c *client) InterviewQuestions(
 			Settings: *settings,
 		},
 	}
+	result.Request.Settings.Engine = StringPtr(settings.engine())
 
-	// Will only be one result max really
-	for _, ch := range resp.Choices {
-		items := parseInterviewChoice(ch, options.Shuffle)
+	result.Questions = collectInterviewQuestions(choices, options.Shuffle, quesCap)
 
-		if items != nil {
-			// result.Questions = append(result.Questions, items...)
-			for _, qu := range items {
-				if len(result.Questions) == quesCap {
-					break
-				}
+	result.Duration = time.Since(start)
 
-				// Index is mostly for shuffle case to reset
-				qu.Index = len(result.Questions) + 1
-				result.Questions = append(result.Questions, qu)
-			}
+	return result, err
+}
+
+// errInterviewCapReached stops a stream once enough questions have been emitted
+var errInterviewCapReached = errors.New("interview question cap reached")
+
+// interviewStreamParser splits streamed text into lines, emitting each line that is a question once it is complete
+type interviewStreamParser struct {
+	partial    string
+	emitted    int
+	cap        int
+	onQuestion func(InterviewQuestion)
+}
+
+// write adds streamed text, returning errInterviewCapReached once cap questions have been emitted
+func (p *interviewStreamParser) write(text string) error {
+	p.partial += text
+	for {
+		end := strings.Index(p.partial, "\n")
+		if end < 0 {
+			return nil
+		}
+		line := p.partial[:end]
+		p.partial = p.partial[end+1:]
+		if err := p.emit(line, false); err != nil {
+			return err
 		}
 	}
+}
+
+// finish emits the last line, which has no trailing new line
+func (p *interviewStreamParser) finish(finishReason string) {
+	_ = p.emit(p.partial, finishReason == FinishReasonLength)
+	p.partial = ""
+}
+
+func (p *interviewStreamParser) emit(line string, truncated bool) error {
+	if p.emitted >= p.cap {
+		return errInterviewCapReached
+	}
+	if !isInterviewQuestion(line, truncated) {
+		return nil
+	}
+	p.emitted++
+	p.onQuestion(InterviewQuestion{
+		Index:    p.emitted,
+		Question: parseText(line),
+	})
+	if p.emitted >= p.cap {
+		return errInterviewCapReached
+	}
+	return nil
+}
 
-	result.Duration = time.Since(start)
+func (c *client) InterviewQuestionsStream(
+	ctx context.Context,
+	input InterviewInput,
+	settings *InterviewRequestSettings,
+	options *InterviewOptions,
+	onQuestion func(InterviewQuestion)) error {
 
-	return result, err
+	prompt, options, err := prepareInterview(input, settings, options)
+	if err != nil {
+		return err
+	}
+
+	parser := &interviewStreamParser{cap: options.GetCap(), onQuestion: onQuestion}
+	var finishReason string
+
+	if engine := settings.engine(); !isChatModel(engine) {
+		err = c.CompletionStreamWithEngine(ctx, engine, mapInterviewSettings(settings, prompt),
+			func(resp *CompletionResponse) error {
+				for _, ch := range resp.Choices {
+					if ch.FinishReason != "" {
+						finishReason = ch.FinishReason
+					}
+					if err := parser.write(ch.Text); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+	} else {
+		// the chat stream can't be stopped early, so once the cap is reached the rest of it is ignored
+		err = c.ChatCompletionStream(ctx, mapInterviewChatSettings(settings, prompt),
+			func(resp *ChatCompletionStreamResponse) {
+				for _, ch := range resp.Choices {
+					if ch.FinishReason != "" {
+						finishReason = ch.FinishReason
+					}
+					_ = parser.write(ch.Delta.Content)
+				}
+			})
+	}
+	if errors.Is(err, errInterviewCapReached) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	parser.finish(finishReason)
+	return nil
 }
 
 func stripLeadingNumbers(question string) string {
@@ -302,14 +455,65 @@ func stripLeadingNumber(question, punc string) string {
 func parseText(question string) string {
 	ques := strings.TrimSpace(question)
 
-	if strings.HasPrefix(ques, "-") {
-		ques = ques[1:]
+	for _, bullet := range interviewBullets {
+		if strings.HasPrefix(ques, bullet) {
+			ques = ques[len(bullet):]
+			break
+		}
 	}
 
 	ques = stripLeadingNumbers(ques)
 	return strings.TrimSpace(ques)
 }
 
+// minInterviewQuestionLength filters out noise such as headings or stray fragments from lines without a question mark
+const minInterviewQuestionLength = 10
+
+var (
+	interviewBullets = []string{"-", "*", "•"}
+
+	// interrogatives are words that start a question phrased without a question mark
+	interrogatives = map[string]bool{
+		"are": true, "can": true, "could": true, "did": true, "do": true, "does": true, "have": true, "has": true,
+		"how": true, "is": true, "what": true, "when": true, "where": true, "which": true, "who": true,
+		"whose": true, "why": true, "will": true, "would": true,
+	}
+)
+
+// hasListMarker returns true when line starts with a bullet or a number such as "1." or "2)"
+func hasListMarker(line string) bool {
+	for _, bullet := range interviewBullets {
+		if strings.HasPrefix(line, bullet) {
+			return true
+		}
+	}
+	return stripLeadingNumbers(line) != strings.TrimSpace(line)
+}
+
+// isInterviewQuestion returns true when a line of generated text looks like an interview question: it ends with a
+// question mark, is a numbered or bulleted list item, or is a sentence starting with an interrogative word.
+// A line that may have been cut off, because the completion ran out of tokens, has to end with "?" or ".".
+func isInterviewQuestion(line string, truncated bool) bool {
+	line = strings.TrimSpace(line)
+	if strings.HasSuffix(line, "?") {
+		return len(parseText(line)) > 1
+	}
+	if len(parseText(line)) < minInterviewQuestionLength {
+		return false
+	}
+	if truncated && !strings.HasSuffix(line, ".") {
+		return false
+	}
+	if hasListMarker(line) {
+		return true
+	}
+	if strings.HasSuffix(line, ".") {
+		words := strings.Fields(parseText(line))
+		return len(words) > 0 && interrogatives[strings.ToLower(words[0])]
+	}
+	return false
+}
+
 func Shuffle(questions []InterviewQuestion) {
 	for len(questions) > 0 {
 		n := len(questions)
@@ -319,6 +523,40 @@ func Shuffle(questions []InterviewQuestion) {
 	}
 }
 
+// collectInterviewQuestions merges the questions of every choice, in choice index order, dropping questions that
+// appear in more than one choice. When shuffle is set the merged questions are shuffled before at most quesCap of
+// them are kept, and the kept questions are indexed from 1.
+func collectInterviewQuestions(choices []CompletionResponseChoice, shuffle bool, quesCap int) []InterviewQuestion {
+	ordered := make([]CompletionResponseChoice, len(choices))
+	copy(ordered, choices)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Index < ordered[j].Index })
+
+	var questions []InterviewQuestion
+	seen := make(map[string]bool)
+	for _, ch := range ordered {
+		for _, qu := range parseInterviewChoice(ch, false) {
+			key := strings.ToLower(qu.Question)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			questions = append(questions, qu)
+		}
+	}
+
+	if shuffle {
+		Shuffle(questions)
+	}
+	if len(questions) > quesCap {
+		questions = questions[:quesCap]
+	}
+	// Index is mostly for shuffle case to reset
+	for i := range questions {
+		questions[i].Index = i + 1
+	}
+	return questions
+}
+
 func parseInterviewChoice(ch CompletionResponseChoice, shuffle bool) []InterviewQuestion {
 	var data []InterviewQuestion
 
@@ -326,11 +564,13 @@ func parseInterviewChoice(ch CompletionResponseChoice, shuffle bool) []Interview
 		return nil
 	}
 
-	parts := strings.Split(ch.Text, "\n")
+	parts := strings.Split(strings.TrimSpace(ch.Text), "\n")
+
+	for i, part := range parts {
+		// Last question can be truncated when the completion hit the token limit
+		truncated := i == len(parts)-1 && ch.FinishReason == FinishReasonLength
 
-	for _, part := range parts {
-		// Last question can be truncated. Might also need to check ch.FinishReason for length later
-		if len(part) > 0 && strings.HasSuffix(part, "?") {
+		if isInterviewQuestion(part, truncated) {
 			ques := parseText(part)
 
 			data = append(data, InterviewQuestion{
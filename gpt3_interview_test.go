@@ -1,6 +1,7 @@
 package gpt3
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -76,3 +77,196 @@ func TestStripLeadingNumbers(t *testing.T) {
 		})
 	}
 }
+
+func TestQuestionText(t *testing.T) {
+	type testCase struct {
+		name      string
+		questions []string
+		expected  string
+	}
+
+	testCases := []testCase{
+		{"No questions", nil, ""},
+		{"One question", []string{"Why this role?"}, "Why this role?"},
+		{
+			"Three questions",
+			[]string{"Why this role?", "What are your strengths?", "Where do you see yourself in 5 years?"},
+			"Why this role?\n\nWhat are your strengths?\n\nWhere do you see yourself in 5 years?",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := &InterviewResponse{}
+			for i, q := range tc.questions {
+				response.Questions = append(response.Questions, InterviewQuestion{Index: i + 1, Question: q})
+			}
+
+			result := response.QuestionText()
+
+			if result != tc.expected {
+				t.Errorf("\nGot: '%s'\nExpected: '%s'", result, tc.expected)
+				return
+			}
+		})
+	}
+}
+
+func TestMapInterviewSettingsDefaults(t *testing.T) {
+	request := mapInterviewSettings(&InterviewRequestSettings{MaxTokens: IntPtr(75)}, "prompt")
+
+	if *request.MaxTokens != 75 {
+		t.Errorf("MaxTokens: got %d, expected 75", *request.MaxTokens)
+	}
+	if *request.FrequencyPenalty != InterviewDefaultFrequencyPenalty {
+		t.Errorf("FrequencyPenalty: got %v, expected %v", *request.FrequencyPenalty, InterviewDefaultFrequencyPenalty)
+	}
+	if *request.PresencePenalty != InterviewDefaultPresencePenalty {
+		t.Errorf("PresencePenalty: got %v, expected %v", *request.PresencePenalty, InterviewDefaultPresencePenalty)
+	}
+	if *request.Temperature != InterviewDefaultTemperature {
+		t.Errorf("Temperature: got %v, expected %v", *request.Temperature, InterviewDefaultTemperature)
+	}
+	if *request.TopP != InterviewDefaultTopP {
+		t.Errorf("TopP: got %v, expected %v", *request.TopP, InterviewDefaultTopP)
+	}
+
+	request = mapInterviewSettings(&InterviewRequestSettings{PresencePenalty: Float32Ptr(0)}, "prompt")
+	if *request.PresencePenalty != 0 {
+		t.Errorf("PresencePenalty: got %v, expected 0", *request.PresencePenalty)
+	}
+	if *request.MaxTokens != InterviewDefaultMaxTokens {
+		t.Errorf("MaxTokens: got %d, expected %d", *request.MaxTokens, InterviewDefaultMaxTokens)
+	}
+}
+
+func TestParseInterviewChoice(t *testing.T) {
+	type testCase struct {
+		name     string
+		choice   CompletionResponseChoice
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			"Question marks",
+			CompletionResponseChoice{Text: "\n\n1. Why Go?\n2. What is a goroutine?", FinishReason: FinishReasonStop},
+			[]string{"Why Go?", "What is a goroutine?"},
+		},
+		{
+			"Mixed formatting",
+			CompletionResponseChoice{
+				Text: "Questions:\n" +
+					"1) Describe a time you led a team.\n" +
+					"- Tell me about a difficult bug you fixed\n" +
+					"• Walk me through your resume.\n" +
+					"How do you handle conflicting priorities.\n" +
+					"I think these are good questions.\n" +
+					"3. Ok.\n" +
+					"What motivates you?",
+				FinishReason: FinishReasonStop,
+			},
+			[]string{
+				"Describe a time you led a team.",
+				"Tell me about a difficult bug you fixed",
+				"Walk me through your resume.",
+				"How do you handle conflicting priorities.",
+				"What motivates you?",
+			},
+		},
+		{
+			"Truncated last line",
+			CompletionResponseChoice{
+				Text:         "1. Describe your ideal team.\n2. What are your strengths?\n3. Tell me about a time you",
+				FinishReason: FinishReasonLength,
+			},
+			[]string{"Describe your ideal team.", "What are your strengths?"},
+		},
+		{
+			"Complete last line",
+			CompletionResponseChoice{
+				Text:         "1. Describe your ideal team.\n2. Tell me about a time you failed.",
+				FinishReason: FinishReasonLength,
+			},
+			[]string{"Describe your ideal team.", "Tell me about a time you failed."},
+		},
+		{
+			"No questions",
+			CompletionResponseChoice{Text: "Sorry, I can't help with that", FinishReason: FinishReasonStop},
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result []string
+			for i, q := range parseInterviewChoice(tc.choice, false) {
+				if q.Index != i+1 {
+					t.Errorf("Index: got %d, expected %d", q.Index, i+1)
+				}
+				result = append(result, q.Question)
+			}
+
+			if strings.Join(result, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("\nGot: %q\nExpected: %q", result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCollectInterviewQuestions(t *testing.T) {
+	choices := []CompletionResponseChoice{
+		{Index: 1, Text: "1. What are your strengths?\n2. Why Go?\n3. Describe your ideal team."},
+		{Index: 0, Text: "1. Why Go?\n2. How do you test code?\n3. what are your strengths?"},
+	}
+
+	type testCase struct {
+		cap      int
+		expected []string
+	}
+
+	testCases := []testCase{
+		{10, []string{"Why Go?", "How do you test code?", "what are your strengths?", "Describe your ideal team."}},
+		{3, []string{"Why Go?", "How do you test code?", "what are your strengths?"}},
+		{1, []string{"Why Go?"}},
+	}
+
+	for _, tc := range testCases {
+		var result []string
+		for i, q := range collectInterviewQuestions(choices, false, tc.cap) {
+			if q.Index != i+1 {
+				t.Errorf("Index: got %d, expected %d", q.Index, i+1)
+			}
+			result = append(result, q.Question)
+		}
+
+		if strings.Join(result, "|") != strings.Join(tc.expected, "|") {
+			t.Errorf("cap %d\nGot: %q\nExpected: %q", tc.cap, result, tc.expected)
+		}
+	}
+
+	if shuffled := collectInterviewQuestions(choices, true, 3); len(shuffled) != 3 {
+		t.Errorf("Shuffled: got %d questions, expected 3", len(shuffled))
+	}
+}
+
+func TestGetInterviewPrompt(t *testing.T) {
+	type testCase struct {
+		title    string
+		desc     string
+		expected string
+	}
+
+	testCases := []testCase{
+		{"Welder", "Builds ships", "Create a list of questions for my interview with a Welder, Builds ships"},
+		{"Welder", "", "Create a list of questions for my interview with a Welder"},
+		{"", "Builds\nships", "Create a list of questions for my interview with a job description of Builds ships"},
+		{"", "", ""},
+	}
+
+	for _, tc := range testCases {
+		if prompt := getInterviewPrompt(tc.title, tc.desc); prompt != tc.expected {
+			t.Errorf("expected prompt %q, got %q", tc.expected, prompt)
+		}
+	}
+}
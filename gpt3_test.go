@@ -2,12 +2,20 @@ package gpt3_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/teamjobot/go-gpt3"
@@ -56,6 +64,34 @@ func TestRequestCreationFails(t *testing.T) {
 			},
 			"Get \"https://api.openai.com/v1/engines/davinci\": request error",
 		},
+		{
+			"Models",
+			func() (interface{}, error) {
+				return client.Models(ctx)
+			},
+			"Get \"https://api.openai.com/v1/models\": request error",
+		},
+		{
+			"Ping",
+			func() (interface{}, error) {
+				return nil, client.Ping(ctx)
+			},
+			"Get \"https://api.openai.com/v1/models?limit=1\": request error",
+		},
+		{
+			"Model",
+			func() (interface{}, error) {
+				return client.Model(ctx, gpt3.TextDavinci003Engine)
+			},
+			"Get \"https://api.openai.com/v1/models/text-davinci-003\": request error",
+		},
+		{
+			"DeleteModel",
+			func() (interface{}, error) {
+				return client.DeleteModel(ctx, "curie:ft-acmeco-2021-03-03-21-44-20")
+			},
+			"Delete \"https://api.openai.com/v1/models/curie:ft-acmeco-2021-03-03-21-44-20\": request error",
+		},
 		{
 			"ChatCompletion",
 			func() (interface{}, error) {
@@ -63,10 +99,57 @@ func TestRequestCreationFails(t *testing.T) {
 			},
 			"Post \"https://api.openai.com/v1/chat/completions\": request error",
 		},
+		{
+			"ChatCompletionRaw",
+			func() (interface{}, error) {
+				_, rsp, err := client.ChatCompletionRaw(ctx, gpt3.ChatCompletionRequest{})
+				return rsp, err
+			},
+			"Post \"https://api.openai.com/v1/chat/completions\": request error",
+		},
+		{
+			"ChatCompletionStream",
+			func() (interface{}, error) {
+				var rsp *gpt3.ChatCompletionStreamResponse
+				onData := func(data *gpt3.ChatCompletionStreamResponse) {
+					rsp = data
+				}
+				err := client.ChatCompletionStream(ctx, gpt3.ChatCompletionRequest{}, onData)
+				return rsp, err
+			},
+			"Post \"https://api.openai.com/v1/chat/completions\": request error",
+		},
+		{
+			"ChatCompletionStreamWithUsage",
+			func() (interface{}, error) {
+				var rsp *gpt3.ChatCompletionStreamResponse
+				onData := func(data *gpt3.ChatCompletionStreamResponse) {
+					rsp = data
+				}
+				_, err := client.ChatCompletionStreamWithUsage(ctx, gpt3.ChatCompletionRequest{}, onData)
+				return rsp, err
+			},
+			"Post \"https://api.openai.com/v1/chat/completions\": request error",
+		},
+		{
+			"ChatCompletionStreamCollect",
+			func() (interface{}, error) {
+				return client.ChatCompletionStreamCollect(ctx, gpt3.ChatCompletionRequest{}, nil)
+			},
+			"Post \"https://api.openai.com/v1/chat/completions\": request error",
+		},
 		{
 			"Completion",
 			func() (interface{}, error) {
-				return client.Completion(ctx, gpt3.CompletionRequest{})
+				return client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+			},
+			"Post \"https://api.openai.com/v1/engines/davinci/completions\": request error",
+		},
+		{
+			"CompletionRaw",
+			func() (interface{}, error) {
+				_, rsp, err := client.CompletionRaw(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+				return rsp, err
 			},
 			"Post \"https://api.openai.com/v1/engines/davinci/completions\": request error",
 		},
@@ -74,31 +157,68 @@ func TestRequestCreationFails(t *testing.T) {
 			"CompletionStream",
 			func() (interface{}, error) {
 				var rsp *gpt3.CompletionResponse
-				onData := func(data *gpt3.CompletionResponse) {
+				onData := func(data *gpt3.CompletionResponse) error {
 					rsp = data
+					return nil
+				}
+				return rsp, client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, onData)
+			},
+			"Post \"https://api.openai.com/v1/engines/davinci/completions\": request error",
+		},
+		{
+			"CompletionStreamText",
+			func() (interface{}, error) {
+				_, err := client.CompletionStreamText(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, nil)
+				return nil, err
+			},
+			"Post \"https://api.openai.com/v1/engines/davinci/completions\": request error",
+		},
+		{
+			"CompletionStreamReader",
+			func() (interface{}, error) {
+				stream, err := client.CompletionStreamReader(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+				if err != nil {
+					return nil, err
 				}
-				return rsp, client.CompletionStream(ctx, gpt3.CompletionRequest{}, onData)
+				defer stream.Close()
+				return stream.Recv()
 			},
 			"Post \"https://api.openai.com/v1/engines/davinci/completions\": request error",
 		},
 		{
 			"CompletionWithEngine",
 			func() (interface{}, error) {
-				return client.CompletionWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{})
+				return client.CompletionWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{Prompt: []string{"test"}})
 			},
 			"Post \"https://api.openai.com/v1/engines/ada/completions\": request error",
 		},
+		{
+			"ContentFilter",
+			func() (interface{}, error) {
+				_, err := client.ContentFilter(ctx, "test")
+				return nil, err
+			},
+			"Post \"https://api.openai.com/v1/engines/content-filter-alpha/completions\": request error",
+		},
 		{
 			"CompletionStreamWithEngine",
 			func() (interface{}, error) {
 				var rsp *gpt3.CompletionResponse
-				onData := func(data *gpt3.CompletionResponse) {
+				onData := func(data *gpt3.CompletionResponse) error {
 					rsp = data
+					return nil
 				}
-				return rsp, client.CompletionStreamWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{}, onData)
+				return rsp, client.CompletionStreamWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{Prompt: []string{"test"}}, onData)
 			},
 			"Post \"https://api.openai.com/v1/engines/ada/completions\": request error",
 		},
+		{
+			"InsertWithEngine",
+			func() (interface{}, error) {
+				return client.InsertWithEngine(ctx, gpt3.TextDavinci003Engine, "func add(a, b int) int {", "}", gpt3.CompletionRequest{})
+			},
+			"Post \"https://api.openai.com/v1/engines/text-davinci-003/completions\": request error",
+		},
 		{
 			"Edits",
 			func() (interface{}, error) {
@@ -127,6 +247,131 @@ func TestRequestCreationFails(t *testing.T) {
 			},
 			"Post \"https://api.openai.com/v1/embeddings\": request error",
 		},
+		{
+			"EmbeddingsBatch",
+			func() (interface{}, error) {
+				return client.EmbeddingsBatch(ctx, gpt3.TextEmbeddingAda002, []string{"test"})
+			},
+			"Post \"https://api.openai.com/v1/embeddings\": request error",
+		},
+		{
+			"Moderations",
+			func() (interface{}, error) {
+				return client.Moderations(ctx, gpt3.ModerationRequest{})
+			},
+			"Post \"https://api.openai.com/v1/moderations\": request error",
+		},
+		{
+			"CreateImage",
+			func() (interface{}, error) {
+				return client.CreateImage(ctx, gpt3.ImageRequest{})
+			},
+			"Post \"https://api.openai.com/v1/images/generations\": request error",
+		},
+		{
+			"CreateImageEdit",
+			func() (interface{}, error) {
+				return client.CreateImageEdit(ctx, gpt3.ImageEditRequest{Image: bytes.NewBufferString("png")})
+			},
+			"Post \"https://api.openai.com/v1/images/edits\": request error",
+		},
+		{
+			"CreateImageVariation",
+			func() (interface{}, error) {
+				return client.CreateImageVariation(ctx, gpt3.ImageVariationRequest{Image: bytes.NewBufferString("png")})
+			},
+			"Post \"https://api.openai.com/v1/images/variations\": request error",
+		},
+		{
+			"CreateTranscription",
+			func() (interface{}, error) {
+				return client.CreateTranscription(ctx, gpt3.AudioRequest{
+					File:     bytes.NewBufferString("audio"),
+					FileName: "audio.mp3",
+				})
+			},
+			"Post \"https://api.openai.com/v1/audio/transcriptions\": request error",
+		},
+		{
+			"CreateTranslation",
+			func() (interface{}, error) {
+				return client.CreateTranslation(ctx, gpt3.AudioRequest{
+					File:     bytes.NewBufferString("audio"),
+					FileName: "audio.mp3",
+				})
+			},
+			"Post \"https://api.openai.com/v1/audio/translations\": request error",
+		},
+		{
+			"UploadFile",
+			func() (interface{}, error) {
+				return client.UploadFile(ctx, gpt3.FileRequest{
+					File:     bytes.NewBufferString("{}"),
+					FileName: "training.jsonl",
+				})
+			},
+			"Post \"https://api.openai.com/v1/files\": request error",
+		},
+		{
+			"ListFiles",
+			func() (interface{}, error) {
+				return client.ListFiles(ctx)
+			},
+			"Get \"https://api.openai.com/v1/files\": request error",
+		},
+		{
+			"RetrieveFile",
+			func() (interface{}, error) {
+				return client.RetrieveFile(ctx, "file-123")
+			},
+			"Get \"https://api.openai.com/v1/files/file-123\": request error",
+		},
+		{
+			"DeleteFile",
+			func() (interface{}, error) {
+				return client.DeleteFile(ctx, "file-123")
+			},
+			"Delete \"https://api.openai.com/v1/files/file-123\": request error",
+		},
+		{
+			"CreateFineTune",
+			func() (interface{}, error) {
+				return client.CreateFineTune(ctx, gpt3.FineTuneRequest{TrainingFile: "file-123"})
+			},
+			"Post \"https://api.openai.com/v1/fine-tunes\": request error",
+		},
+		{
+			"ListFineTunes",
+			func() (interface{}, error) {
+				return client.ListFineTunes(ctx)
+			},
+			"Get \"https://api.openai.com/v1/fine-tunes\": request error",
+		},
+		{
+			"RetrieveFineTune",
+			func() (interface{}, error) {
+				return client.RetrieveFineTune(ctx, "ft-123")
+			},
+			"Get \"https://api.openai.com/v1/fine-tunes/ft-123\": request error",
+		},
+		{
+			"CancelFineTune",
+			func() (interface{}, error) {
+				return client.CancelFineTune(ctx, "ft-123")
+			},
+			"Post \"https://api.openai.com/v1/fine-tunes/ft-123/cancel\": request error",
+		},
+		{
+			"ListFineTuneEvents",
+			func() (interface{}, error) {
+				var rsp *gpt3.FineTuneEvent
+				onEvent := func(event *gpt3.FineTuneEvent) {
+					rsp = event
+				}
+				return rsp, client.ListFineTuneEvents(ctx, "ft-123", true, onEvent)
+			},
+			"Get \"https://api.openai.com/v1/fine-tunes/ft-123/events?stream=true\": request error",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -184,6 +429,56 @@ func TestResponses(t *testing.T) {
 				Ready:  true,
 			},
 		},
+		{
+			"Models",
+			func() (interface{}, error) {
+				return client.Models(ctx)
+			},
+			&gpt3.ModelsResponse{
+				Object: "list",
+				Data: []gpt3.ModelObject{
+					{
+						ID:      gpt3.TextDavinci003Engine,
+						Object:  "model",
+						Created: 1669599635,
+						OwnedBy: "openai-internal",
+						Permission: []gpt3.ModelPermission{{
+							ID:            "modelperm-123",
+							Object:        "model_permission",
+							AllowSampling: true,
+							AllowLogprobs: true,
+							AllowView:     true,
+							Organization:  "*",
+						}},
+						Root: gpt3.TextDavinci003Engine,
+					},
+				},
+			},
+		},
+		{
+			"Model",
+			func() (interface{}, error) {
+				return client.Model(ctx, gpt3.TextDavinci003Engine)
+			},
+			&gpt3.ModelObject{
+				ID:      gpt3.TextDavinci003Engine,
+				Object:  "model",
+				Created: 1669599635,
+				OwnedBy: "openai-internal",
+				Root:    gpt3.TextDavinci003Engine,
+			},
+		},
+		{
+			"DeleteModel",
+			func() (interface{}, error) {
+				return client.DeleteModel(ctx, "curie:ft-acmeco-2021-03-03-21-44-20")
+			},
+			&gpt3.DeleteModelResponse{
+				ID:      "curie:ft-acmeco-2021-03-03-21-44-20",
+				Object:  "model",
+				Deleted: true,
+			},
+		},
 		{
 			"ChatCompletion",
 			func() (interface{}, error) {
@@ -198,20 +493,56 @@ func TestResponses(t *testing.T) {
 					{
 						Index:        0,
 						FinishReason: "stop",
-						Message: []gpt3.ChatCompletionResponseMessage{
-							{
-								Role:    "assistant",
-								Content: "output",
-							},
+						Message: gpt3.ChatCompletionResponseMessage{
+							Role:    "assistant",
+							Content: "output",
 						},
 					},
 				},
+				Usage: gpt3.Usage{
+					PromptTokens:     9,
+					CompletionTokens: 12,
+					TotalTokens:      21,
+				},
+			},
+		},
+		{
+			"ChatCompletionRaw",
+			func() (interface{}, error) {
+				_, rsp, err := client.ChatCompletionRaw(ctx, gpt3.ChatCompletionRequest{})
+				return rsp, err
+			},
+			&gpt3.ChatCompletionResponse{
+				ID:    "chatcmpl-123",
+				Model: "gpt-3.5-turbo",
+				Choices: []gpt3.ChatCompletionResponseChoice{
+					{Message: gpt3.ChatCompletionResponseMessage{Role: "assistant", Content: "output"}},
+				},
+			},
+		},
+		{
+			"ChatCompletionStream",
+			func() (interface{}, error) {
+				var rsp *gpt3.ChatCompletionStreamResponse
+				onData := func(data *gpt3.ChatCompletionStreamResponse) {
+					rsp = data
+				}
+				err := client.ChatCompletionStream(ctx, gpt3.ChatCompletionRequest{}, onData)
+				return rsp, err
+			},
+			nil, // streaming responses are tested separately
+		},
+		{
+			"ChatCompletionStreamCollect",
+			func() (interface{}, error) {
+				return client.ChatCompletionStreamCollect(ctx, gpt3.ChatCompletionRequest{}, nil)
 			},
+			nil, // streaming responses are tested separately
 		},
 		{
 			"Completion",
 			func() (interface{}, error) {
-				return client.Completion(ctx, gpt3.CompletionRequest{})
+				return client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
 			},
 			&gpt3.CompletionResponse{
 				ID:      "123",
@@ -224,23 +555,53 @@ func TestResponses(t *testing.T) {
 						FinishReason: "stop",
 					},
 				},
+				Usage: &gpt3.Usage{
+					PromptTokens:     5,
+					CompletionTokens: 7,
+					TotalTokens:      12,
+				},
+			},
+		},
+		{
+			"CompletionRaw",
+			func() (interface{}, error) {
+				_, rsp, err := client.CompletionRaw(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+				return rsp, err
+			},
+			&gpt3.CompletionResponse{
+				ID:      "123",
+				Model:   "davinci-12",
+				Choices: []gpt3.CompletionResponseChoice{{Text: "output"}},
 			},
 		},
 		{
 			"CompletionStream",
 			func() (interface{}, error) {
 				var rsp *gpt3.CompletionResponse
-				onData := func(data *gpt3.CompletionResponse) {
+				onData := func(data *gpt3.CompletionResponse) error {
 					rsp = data
+					return nil
+				}
+				return rsp, client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, onData)
+			},
+			nil, // streaming responses are tested separately
+		},
+		{
+			"CompletionStreamReader",
+			func() (interface{}, error) {
+				stream, err := client.CompletionStreamReader(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+				if err != nil {
+					return nil, err
 				}
-				return rsp, client.CompletionStream(ctx, gpt3.CompletionRequest{}, onData)
+				defer stream.Close()
+				return stream.Recv()
 			},
 			nil, // streaming responses are tested separately
 		},
 		{
 			"CompletionWithEngine",
 			func() (interface{}, error) {
-				return client.CompletionWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{})
+				return client.CompletionWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{Prompt: []string{"test"}})
 			},
 			&gpt3.CompletionResponse{
 				ID:      "123",
@@ -259,13 +620,46 @@ func TestResponses(t *testing.T) {
 			"CompletionStreamWithEngine",
 			func() (interface{}, error) {
 				var rsp *gpt3.CompletionResponse
-				onData := func(data *gpt3.CompletionResponse) {
+				onData := func(data *gpt3.CompletionResponse) error {
 					rsp = data
+					return nil
 				}
-				return rsp, client.CompletionStreamWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{}, onData)
+				return rsp, client.CompletionStreamWithEngine(ctx, gpt3.AdaEngine, gpt3.CompletionRequest{Prompt: []string{"test"}}, onData)
 			},
 			nil, // streaming responses are tested separately
 		},
+		{
+			"InsertWithEngine",
+			func() (interface{}, error) {
+				return client.InsertWithEngine(ctx, gpt3.TextDavinci003Engine, "func add(a, b int) int {", "}", gpt3.CompletionRequest{})
+			},
+			&gpt3.CompletionResponse{
+				ID:      "123",
+				Model:   "text-davinci-003",
+				Choices: []gpt3.CompletionResponseChoice{{Text: "\n\treturn a + b\n", FinishReason: "stop"}},
+			},
+		},
+		{
+			"Edits",
+			func() (interface{}, error) {
+				return client.Edits(ctx, gpt3.EditsRequest{})
+			},
+			&gpt3.EditsResponse{
+				Object:  "edit",
+				Created: 123456789,
+				Choices: []gpt3.EditsResponseChoice{
+					{
+						Text:  "What day of the week is it?",
+						Index: 0,
+					},
+				},
+				Usage: gpt3.Usage{
+					PromptTokens:     25,
+					CompletionTokens: 32,
+					TotalTokens:      57,
+				},
+			},
+		},
 		{
 			"Search",
 			func() (interface{}, error) {
@@ -303,9 +697,9 @@ func TestResponses(t *testing.T) {
 			},
 			&gpt3.EmbeddingsResponse{
 				Object: "list",
-				Data: []gpt3.EmbeddingsResult{{
+				Data: []gpt3.Embedding{{
 					Object:    "object",
-					Embedding: []float64{0.1, 0.2, 0.3},
+					Embedding: []float32{0.1, 0.2, 0.3},
 					Index:     0,
 				}},
 				Usage: gpt3.EmbeddingsUsage{
@@ -314,56 +708,279 @@ func TestResponses(t *testing.T) {
 				},
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			t.Run("bad status codes", func(t *testing.T) {
-				for _, code := range []int{400, 401, 404, 422, 500} {
-					// first mock with error with body failure
-					mockResponse := &http.Response{
-						StatusCode: code,
-						Body:       ioutil.NopCloser(errReader(0)),
-					}
-
-					rt.RoundTripReturns(mockResponse, nil)
-					rsp, err := tc.apiCall()
-					assert.Nil(t, rsp)
-					assert.EqualError(t, err, "failed to read from body: read error")
-
-					// then mock with an unknown error string
-					mockResponse = &http.Response{
-						StatusCode: code,
-						Body:       ioutil.NopCloser(bytes.NewBufferString("unknown error")),
-					}
-
-					rt.RoundTripReturns(mockResponse, nil)
-					rsp, err = tc.apiCall()
-					assert.Nil(t, rsp)
-					assert.EqualError(t, err, fmt.Sprintf("[%d:Unexpected] unknown error", code))
-
-					// then mock with an json APIErrorResponse
-					apiErrorResponse := &gpt3.APIErrorResponse{
-						Error: gpt3.APIError{
-							Type:    "test_type",
-							Message: "test message",
-						},
-					}
-
-					data, err := json.Marshal(apiErrorResponse)
-					assert.NoError(t, err)
-
-					mockResponse = &http.Response{
-						StatusCode: code,
-						Body:       ioutil.NopCloser(bytes.NewBuffer(data)),
-					}
-
-					rt.RoundTripReturns(mockResponse, nil)
-					rsp, err = tc.apiCall()
-					assert.Nil(t, rsp)
-					assert.EqualError(t, err, fmt.Sprintf("[%d:test_type] test message", code))
-					apiErrorResponse.Error.StatusCode = code
-					assert.Equal(t, apiErrorResponse.Error, err)
+		{
+			"Moderations",
+			func() (interface{}, error) {
+				return client.Moderations(ctx, gpt3.ModerationRequest{})
+			},
+			&gpt3.ModerationResponse{
+				ID:    "modr-123",
+				Model: "text-moderation-004",
+				Results: []gpt3.ModerationResult{{
+					Flagged: true,
+					Categories: map[string]bool{
+						gpt3.ModerationCategoryHate:     false,
+						gpt3.ModerationCategoryViolence: true,
+					},
+					CategoryScores: map[string]float64{
+						gpt3.ModerationCategoryHate:     0.0012,
+						gpt3.ModerationCategoryViolence: 0.9871,
+					},
+				}},
+			},
+		},
+		{
+			"CreateImage",
+			func() (interface{}, error) {
+				return client.CreateImage(ctx, gpt3.ImageRequest{})
+			},
+			&gpt3.ImageResponse{
+				Created: 1589478378,
+				Data: []gpt3.ImageData{
+					{URL: "https://example.com/image-1.png"},
+					{URL: "https://example.com/image-2.png"},
+				},
+			},
+		},
+		{
+			"CreateImageEdit",
+			func() (interface{}, error) {
+				return client.CreateImageEdit(ctx, gpt3.ImageEditRequest{Image: bytes.NewBufferString("png")})
+			},
+			&gpt3.ImageResponse{
+				Created: 1589478378,
+				Data:    []gpt3.ImageData{{B64JSON: "aW1hZ2U="}},
+			},
+		},
+		{
+			"CreateImageVariation",
+			func() (interface{}, error) {
+				return client.CreateImageVariation(ctx, gpt3.ImageVariationRequest{Image: bytes.NewBufferString("png")})
+			},
+			&gpt3.ImageResponse{
+				Created: 1589478378,
+				Data:    []gpt3.ImageData{{URL: "https://example.com/variation.png"}},
+			},
+		},
+		{
+			"CreateTranscription",
+			func() (interface{}, error) {
+				return client.CreateTranscription(ctx, gpt3.AudioRequest{
+					File:     bytes.NewBufferString("audio"),
+					FileName: "audio.mp3",
+				})
+			},
+			&gpt3.AudioResponse{
+				Text: "Imagine the wildest idea that you've ever had.",
+			},
+		},
+		{
+			"CreateTranslation",
+			func() (interface{}, error) {
+				return client.CreateTranslation(ctx, gpt3.AudioRequest{
+					File:     bytes.NewBufferString("audio"),
+					FileName: "audio.mp3",
+				})
+			},
+			&gpt3.AudioResponse{
+				Text: "Hello, my name is Wolfgang and I come from Germany.",
+			},
+		},
+		{
+			"UploadFile",
+			func() (interface{}, error) {
+				return client.UploadFile(ctx, gpt3.FileRequest{
+					File:     bytes.NewBufferString("{}"),
+					FileName: "training.jsonl",
+					Purpose:  gpt3.FilePurposeFineTune,
+				})
+			},
+			&gpt3.FileObject{
+				ID:        "file-123",
+				Object:    "file",
+				Bytes:     140,
+				CreatedAt: 1613779121,
+				Filename:  "training.jsonl",
+				Purpose:   gpt3.FilePurposeFineTune,
+				Status:    "uploaded",
+			},
+		},
+		{
+			"ListFiles",
+			func() (interface{}, error) {
+				return client.ListFiles(ctx)
+			},
+			&gpt3.FilesResponse{
+				Object: "list",
+				Data: []gpt3.FileObject{{
+					ID:        "file-123",
+					Object:    "file",
+					Bytes:     140,
+					CreatedAt: 1613779121,
+					Filename:  "training.jsonl",
+					Purpose:   gpt3.FilePurposeFineTune,
+					Status:    "processed",
+				}},
+			},
+		},
+		{
+			"RetrieveFile",
+			func() (interface{}, error) {
+				return client.RetrieveFile(ctx, "file-123")
+			},
+			&gpt3.FileObject{
+				ID:        "file-123",
+				Object:    "file",
+				Bytes:     140,
+				CreatedAt: 1613779121,
+				Filename:  "training.jsonl",
+				Purpose:   gpt3.FilePurposeFineTune,
+				Status:    "processed",
+			},
+		},
+		{
+			"DeleteFile",
+			func() (interface{}, error) {
+				return client.DeleteFile(ctx, "file-123")
+			},
+			&gpt3.DeleteFileResponse{
+				ID:      "file-123",
+				Object:  "file",
+				Deleted: true,
+			},
+		},
+		{
+			"CreateFineTune",
+			func() (interface{}, error) {
+				return client.CreateFineTune(ctx, gpt3.FineTuneRequest{TrainingFile: "file-123"})
+			},
+			&gpt3.FineTune{
+				ID:        "ft-123",
+				Object:    "fine-tune",
+				Model:     gpt3.CurieEngine,
+				CreatedAt: 1614807352,
+				UpdatedAt: 1614807352,
+				Status:    gpt3.FineTuneStatusPending,
+				Events: []gpt3.FineTuneEvent{{
+					Object:    "fine-tune-event",
+					CreatedAt: 1614807352,
+					Level:     "info",
+					Message:   "Job enqueued. Waiting for jobs ahead to complete. Queue number: 0.",
+				}},
+				TrainingFiles: []gpt3.FileObject{{ID: "file-123", Purpose: gpt3.FilePurposeFineTune}},
+			},
+		},
+		{
+			"ListFineTunes",
+			func() (interface{}, error) {
+				return client.ListFineTunes(ctx)
+			},
+			&gpt3.FineTunesResponse{
+				Object: "list",
+				Data: []gpt3.FineTune{{
+					ID:             "ft-123",
+					Object:         "fine-tune",
+					Model:          gpt3.CurieEngine,
+					Status:         gpt3.FineTuneStatusSucceeded,
+					FineTunedModel: "curie:ft-acmeco-2021-03-03-21-44-20",
+				}},
+			},
+		},
+		{
+			"RetrieveFineTune",
+			func() (interface{}, error) {
+				return client.RetrieveFineTune(ctx, "ft-123")
+			},
+			&gpt3.FineTune{
+				ID:             "ft-123",
+				Object:         "fine-tune",
+				Model:          gpt3.CurieEngine,
+				Status:         gpt3.FineTuneStatusSucceeded,
+				FineTunedModel: "curie:ft-acmeco-2021-03-03-21-44-20",
+				Hyperparams: gpt3.FineTuneHyperparams{
+					BatchSize:              4,
+					LearningRateMultiplier: 0.1,
+					NEpochs:                4,
+					PromptLossWeight:       0.1,
+				},
+				ResultFiles: []gpt3.FileObject{{ID: "file-456", Filename: "compiled_results.csv"}},
+			},
+		},
+		{
+			"CancelFineTune",
+			func() (interface{}, error) {
+				return client.CancelFineTune(ctx, "ft-123")
+			},
+			&gpt3.FineTune{
+				ID:     "ft-123",
+				Object: "fine-tune",
+				Model:  gpt3.CurieEngine,
+				Status: gpt3.FineTuneStatusCancelled,
+			},
+		},
+		{
+			"ListFineTuneEvents",
+			func() (interface{}, error) {
+				var rsp *gpt3.FineTuneEvent
+				onEvent := func(event *gpt3.FineTuneEvent) {
+					rsp = event
+				}
+				return rsp, client.ListFineTuneEvents(ctx, "ft-123", true, onEvent)
+			},
+			nil, // streaming responses are tested separately
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("bad status codes", func(t *testing.T) {
+				for _, code := range []int{400, 401, 404, 422, 500} {
+					// first mock with error with body failure
+					mockResponse := &http.Response{
+						StatusCode: code,
+						Body:       ioutil.NopCloser(errReader(0)),
+					}
+
+					rt.RoundTripReturns(mockResponse, nil)
+					rsp, err := tc.apiCall()
+					assert.Nil(t, rsp)
+					assert.EqualError(t, err, "failed to read from body: read error")
+
+					// then mock with an unknown error string
+					mockResponse = &http.Response{
+						StatusCode: code,
+						Body:       ioutil.NopCloser(bytes.NewBufferString("unknown error")),
+					}
+
+					rt.RoundTripReturns(mockResponse, nil)
+					rsp, err = tc.apiCall()
+					assert.Nil(t, rsp)
+					assert.EqualError(t, err, fmt.Sprintf("[%d:Unexpected] unknown error", code))
+
+					// then mock with an json APIErrorResponse
+					apiErrorResponse := &gpt3.APIErrorResponse{
+						Error: gpt3.APIError{
+							Type:    "test_type",
+							Message: "test message",
+						},
+					}
+
+					data, err := json.Marshal(apiErrorResponse)
+					assert.NoError(t, err)
+
+					mockResponse = &http.Response{
+						StatusCode: code,
+						Body:       ioutil.NopCloser(bytes.NewBuffer(data)),
+					}
+
+					rt.RoundTripReturns(mockResponse, nil)
+					rsp, err = tc.apiCall()
+					assert.Nil(t, rsp)
+					assert.EqualError(t, err, fmt.Sprintf("[%d:test_type] test message", code))
+					apiErrorResponse.Error.StatusCode = code
+					var apiErr gpt3.APIError
+					assert.True(t, errors.As(err, &apiErr))
+					assert.Equal(t, apiErrorResponse.Error, apiErr)
 				}
 			})
 			t.Run("success code json decode failure", func(t *testing.T) {
@@ -400,4 +1017,2793 @@ func TestResponses(t *testing.T) {
 	}
 }
 
-// TODO: add streaming response tests
+func fakeStreamResponse(events ...string) *http.Response {
+	var body bytes.Buffer
+	for _, event := range events {
+		body.WriteString("data: " + event + "\n\n")
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(&body),
+	}
+}
+
+func TestCompletionStream(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"id":"cmpl-1","object":"text_completion","choices":[{"text":"Hello","index":0}]}`,
+		`{"id":"cmpl-1","object":"text_completion","choices":[{"text":" world","index":0,"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	var text string
+	var last *gpt3.CompletionResponse
+	err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(rsp *gpt3.CompletionResponse) error {
+		text += rsp.Choices[0].Text
+		last = rsp
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello world", text)
+	assert.Nil(t, last.Usage)
+
+	req := rt.RoundTripArgsForCall(0)
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"stream":true`)
+}
+
+func TestCompletionStreamMultiLineData(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			"event: completion\nid: 1\ndata: {\"choices\":[\ndata: {\"text\":\"Hello\"}]}\n\n" +
+				": keep-alive\n\n" +
+				"data: {\"choices\":[{\"text\":\" world\"}]}\n\n" +
+				"data: [DONE]\n\n")),
+	}, nil)
+
+	var text string
+	err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(rsp *gpt3.CompletionResponse) error {
+		text += rsp.Choices[0].Text
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello world", text)
+}
+
+func TestCompletionStreamMultiplePrompts(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	// the chunks of both prompts are interleaved, and the server leaves out the index of the first prompt on one chunk
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"choices":[{"text":"Bonjour","index":0}]}`,
+		`{"choices":[{"text":"Hola","index":1}]}`,
+		`{"choices":[{"text":" le monde"}]}`,
+		`{"choices":[{"text":" mundo","index":1,"finish_reason":"stop"}]}`,
+		`{"choices":[{"text":"!","index":0,"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	texts := make([]strings.Builder, 2)
+	request := gpt3.CompletionRequest{Prompt: []string{"Say hello in French:", "Say hello in Spanish:"}}
+	err := client.CompletionStream(ctx, request, func(rsp *gpt3.CompletionResponse) error {
+		for _, choice := range rsp.Choices {
+			texts[choice.Index].WriteString(choice.Text)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bonjour le monde!", texts[0].String())
+	assert.Equal(t, "Hola mundo", texts[1].String())
+}
+
+func TestCompletionStreamKeepAlive(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"choices":[{"text":"Hello","index":0}]}`,
+		`{"choices":[]}`,
+		`{"choices":[{"text":" world","index":0,"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	var text string
+	var keepAlives int
+	err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(rsp *gpt3.CompletionResponse) error {
+		chunk, ok := rsp.FirstChoiceText()
+		if !ok {
+			keepAlives++
+		}
+		text += chunk
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello world", text)
+	assert.Equal(t, 1, keepAlives)
+}
+
+func TestCompletionStreamText(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"choices":[{"text":"Hello","index":0}]}`,
+		`{"choices":[]}`,
+		`{"choices":[{"text":" world","index":0,"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	chunks := 0
+	text, err := client.CompletionStreamText(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(*gpt3.CompletionResponse) error {
+		chunks++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello world", text)
+	assert.Equal(t, 3, chunks)
+
+	rt.RoundTripReturns(fakeStreamResponse(`{"choices":[{"text":"Hello","index":0}]}`, `{"choices":[{"text":" world","index":0}]}`), nil)
+	stopErr := errors.New("stop")
+	text, err = client.CompletionStreamText(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(*gpt3.CompletionResponse) error {
+		return stopErr
+	})
+	assert.Equal(t, stopErr, err)
+	assert.Equal(t, "Hello", text)
+
+	rt.RoundTripReturns(fakeStreamResponse(`{"choices":[{"text":"Hi","index":0}]}`, "[DONE]"), nil)
+	text, err = client.CompletionStreamText(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi", text)
+}
+
+func TestCompletionStreamKeepAliveComments(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			": keep-alive\n\n" +
+				"data: {\"choices\":[{\"text\":\"Hello\"}]}\n\n" +
+				":\n: keep-alive\n\n" +
+				"data: {\"choices\":[\n: keep-alive\ndata: {\"text\":\" world\"}]}\n\n" +
+				": keep-alive\n" +
+				"data: [DONE]\n\n")),
+	}, nil)
+
+	var chunks []string
+	err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(rsp *gpt3.CompletionResponse) error {
+		text, _ := rsp.FirstChoiceText()
+		chunks = append(chunks, text)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Hello", " world"}, chunks)
+}
+
+func TestCompletionStreamReader(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"id":"cmpl-1","object":"text_completion","choices":[{"text":"Hello","index":0}]}`,
+		`{"id":"cmpl-1","object":"text_completion","choices":[{"text":" world","index":0,"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	stream, err := client.CompletionStreamReader(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	var text string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		text += chunk.Choices[0].Text
+	}
+	assert.NoError(t, stream.Close())
+	assert.Equal(t, "Hello world", text)
+
+	t.Run("closed early", func(t *testing.T) {
+		rt.RoundTripReturns(fakeStreamResponse(
+			`{"choices":[{"text":"Hello","index":0}]}`,
+			`{"choices":[{"text":" world","index":0}]}`,
+			"[DONE]",
+		), nil)
+		stream, err := client.CompletionStreamReader(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		assert.NoError(t, err)
+		chunk, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello", chunk.Choices[0].Text)
+
+		assert.NoError(t, stream.Close())
+		_, err = stream.Recv()
+		assert.Equal(t, context.Canceled, err)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, err := client.CompletionStreamReader(ctx, gpt3.CompletionRequest{})
+		assert.EqualError(t, err, "prompt must have at least one prompt")
+	})
+}
+
+func TestContentFilter(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	type testCase struct {
+		name     string
+		choice   string
+		expected gpt3.FilterLabel
+	}
+
+	testCases := []testCase{
+		{"safe", `{"text":"0"}`, gpt3.FilterLabelSafe},
+		{"sensitive", `{"text":"1"}`, gpt3.FilterLabelSensitive},
+		{"confident unsafe", `{"text":"2","logprobs":{"top_logprobs":[{"2":-0.1,"0":-3}]}}`, gpt3.FilterLabelUnsafe},
+		{"uncertain unsafe", `{"text":"2","logprobs":{"top_logprobs":[{"2":-0.6,"0":-1.2,"1":-0.9}]}}`, gpt3.FilterLabelSensitive},
+		{"uncertain unsafe without alternatives", `{"text":"2","logprobs":{"top_logprobs":[{"2":-0.6}]}}`, gpt3.FilterLabelUnsafe},
+		{"unknown label", `{"text":"x"}`, gpt3.FilterLabelUnsafe},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt.RoundTripReturns(&http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[` + tc.choice + `]}`)),
+			}, nil)
+
+			label, err := client.ContentFilter(ctx, "some text")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, label)
+		})
+	}
+
+	req := rt.RoundTripArgsForCall(0)
+	assert.Equal(t, "https://api.openai.com/v1/engines/content-filter-alpha/completions", req.URL.String())
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"prompt":["\u003c|endoftext|\u003esome text\n--\nLabel:"],"max_tokens":1`)
+	assert.Equal(t, "unsafe", gpt3.FilterLabelUnsafe.String())
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithDefaultEngine(gpt3.TextDavinci003Engine))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"\n\treturn a + b\n"}]}`)),
+	}, nil)
+
+	rsp, err := client.Insert(ctx, "func add(a, b int) int {", "}", gpt3.CompletionRequest{
+		Prompt:    []string{"ignored"},
+		MaxTokens: gpt3.IntPtr(16),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "\n\treturn a + b\n", rsp.Choices[0].Text)
+
+	req := rt.RoundTripArgsForCall(0)
+	assert.Equal(t, "https://api.openai.com/v1/engines/text-davinci-003/completions", req.URL.String())
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"prompt":["func add(a, b int) int {"],"max_tokens":16`)
+	assert.Contains(t, string(body), `"suffix":"}"`)
+
+	_, err = client.InsertWithEngine(ctx, gpt3.DavinciEngine, "a", "b", gpt3.CompletionRequest{})
+	assert.EqualError(t, err, `engine "davinci" doesn't support insertion`)
+	_, err = client.Insert(ctx, "a", "", gpt3.CompletionRequest{})
+	assert.EqualError(t, err, "insertion requires a suffix")
+	assert.Equal(t, 1, rt.RoundTripCallCount())
+}
+
+func TestCompletionStreamMatchedStop(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+	stops := gpt3.StopSequences{"\n\n", "END", "D"}
+
+	type testCase struct {
+		name     string
+		stop     gpt3.StopSequences
+		chunks   []string
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			"reported by the server",
+			stops,
+			[]string{
+				`{"choices":[{"text":"Hello","index":0}]}`,
+				`{"choices":[{"text":"","index":0,"finish_reason":"stop","stop_reason":"END"}]}`,
+			},
+			[]string{"", "END"},
+		},
+		{
+			"not a stop sequence of the request",
+			stops,
+			[]string{
+				`{"choices":[{"text":"Hello","index":0}]}`,
+				`{"choices":[{"text":"","index":0,"finish_reason":"stop","stop_reason":50256}]}`,
+			},
+			[]string{"", ""},
+		},
+		{
+			"not reported, as by OpenAI",
+			stops,
+			[]string{
+				`{"choices":[{"text":"Hello","index":0}]}`,
+				`{"choices":[{"text":"","index":0,"finish_reason":"stop"}]}`,
+			},
+			[]string{"", ""},
+		},
+		{
+			"length cutoff",
+			stops,
+			[]string{
+				`{"choices":[{"text":"Hello","index":0}]}`,
+				`{"choices":[{"text":" wor","index":0,"finish_reason":"length","stop_reason":null}]}`,
+			},
+			[]string{"", ""},
+		},
+		{
+			"the only stop sequence",
+			gpt3.StopString("END"),
+			[]string{
+				`{"choices":[{"text":"Hello","index":0}]}`,
+				`{"choices":[{"text":"","index":0,"finish_reason":"stop"}]}`,
+			},
+			[]string{"", "END"},
+		},
+		{
+			"length cutoff with the only stop sequence",
+			gpt3.StopString("END"),
+			[]string{
+				`{"choices":[{"text":"Hello","index":0}]}`,
+				`{"choices":[{"text":" wor","index":0,"finish_reason":"length"}]}`,
+			},
+			[]string{"", ""},
+		},
+		{
+			"no stop sequences",
+			nil,
+			[]string{
+				`{"choices":[{"text":"Hello","index":0}]}`,
+				`{"choices":[{"text":"","index":0,"finish_reason":"stop"}]}`,
+			},
+			[]string{"", ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt.RoundTripReturns(fakeStreamResponse(append(tc.chunks, "[DONE]")...), nil)
+			request := gpt3.CompletionRequest{Prompt: []string{"test"}, Stop: tc.stop}
+
+			var matched []string
+			err := client.CompletionStream(ctx, request, func(rsp *gpt3.CompletionResponse) error {
+				matched = append(matched, rsp.Choices[0].MatchedStop)
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, matched)
+		})
+	}
+}
+
+func TestCompletionStreamEcho(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"choices":[{"text":"Say","index":0},{"text":"Count:","index":1}]}`,
+		`{"choices":[{"text":" hi: Hello","index":0},{"text":" 1","index":1}]}`,
+		`{"choices":[{"text":" there","index":0,"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	var echoed, generated [2]string
+	request := gpt3.CompletionRequest{Prompt: []string{"Say hi:", "Count:"}, Echo: true}
+	err := client.CompletionStream(ctx, request, func(rsp *gpt3.CompletionResponse) error {
+		for _, choice := range rsp.Choices {
+			echoed[choice.Index] += choice.Text[:choice.EchoLength]
+			generated[choice.Index] += choice.GeneratedText()
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [2]string{"Say hi:", "Count:"}, echoed)
+	assert.Equal(t, [2]string{" Hello there", " 1"}, generated)
+
+	t.Run("non-streamed", func(t *testing.T) {
+		rt.RoundTripReturns(&http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"Say hi: Hello","index":0}]}`)),
+		}, nil)
+		resp, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"Say hi:"}, Echo: true})
+		assert.NoError(t, err)
+		assert.Equal(t, " Hello", resp.Choices[0].GeneratedText())
+	})
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"Roses"}}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":" are blue"}}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	var role, content, finishReason string
+	err := client.ChatCompletionStream(ctx, gpt3.ChatCompletionRequest{}, func(rsp *gpt3.ChatCompletionStreamResponse) {
+		choice := rsp.Choices[0]
+		if choice.Delta.Role != "" {
+			role = choice.Delta.Role
+		}
+		content += choice.Delta.Content
+		finishReason = choice.FinishReason
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "assistant", role)
+	assert.Equal(t, "Roses are blue", content)
+	assert.Equal(t, "stop", finishReason)
+
+	t.Run("invalid json", func(t *testing.T) {
+		rt.RoundTripReturns(fakeStreamResponse("{invalid"), nil)
+		err := client.ChatCompletionStream(ctx, gpt3.ChatCompletionRequest{}, func(*gpt3.ChatCompletionStreamResponse) {})
+		assert.EqualError(t, err, "invalid json stream data: invalid character 'i' looking for beginning of object key string")
+	})
+}
+
+func TestChatCompletionStreamUsage(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}],"usage":null}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":null}`,
+		`{"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":9,"completion_tokens":1,"total_tokens":10}}`,
+		"[DONE]",
+	), nil)
+
+	chunks := 0
+	usage, err := client.ChatCompletionStreamWithUsage(ctx, gpt3.ChatCompletionRequest{
+		StreamOptions: &gpt3.StreamOptions{IncludeUsage: true},
+	}, func(rsp *gpt3.ChatCompletionStreamResponse) {
+		// the usage chunk has no choices, so it must not be passed on
+		assert.NotEmpty(t, rsp.Choices)
+		chunks++
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, chunks)
+	assert.Equal(t, &gpt3.Usage{PromptTokens: 9, CompletionTokens: 1, TotalTokens: 10}, usage)
+
+	body, err := ioutil.ReadAll(rt.RoundTripArgsForCall(0).Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"stream_options":{"include_usage":true}`)
+}
+
+func TestChatCompletionStreamCollect(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"id":"chatcmpl-1","created":123,"model":"gpt-3.5-turbo-0613","choices":[{"index":0,"delta":{"role":"assistant"}},{"index":1,"delta":{"role":"assistant"}}]}`,
+		`{"id":"chatcmpl-1","created":123,"model":"gpt-3.5-turbo-0613","choices":[{"index":0,"delta":{"content":"Roses"}},{"index":1,"delta":{"content":"Violets"}}]}`,
+		`{"id":"chatcmpl-1","created":123,"model":"gpt-3.5-turbo-0613","choices":[{"index":0,"delta":{"content":" are red"}}]}`,
+		`{"id":"chatcmpl-1","created":123,"model":"gpt-3.5-turbo-0613","choices":[{"index":0,"delta":{},"finish_reason":"stop"},{"index":1,"delta":{},"finish_reason":"length"}]}`,
+		`{"id":"chatcmpl-1","created":123,"model":"gpt-3.5-turbo-0613","choices":[],"usage":{"prompt_tokens":9,"completion_tokens":4,"total_tokens":13}}`,
+		"[DONE]",
+	), nil)
+
+	var deltas []string
+	resp, err := client.ChatCompletionStreamCollect(ctx, gpt3.ChatCompletionRequest{
+		N:             2,
+		StreamOptions: &gpt3.StreamOptions{IncludeUsage: true},
+	}, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Roses", " are red"}, deltas)
+	assert.Equal(t, &gpt3.ChatCompletionResponse{
+		ID:      "chatcmpl-1",
+		Object:  "chat.completion",
+		Created: 123,
+		Model:   "gpt-3.5-turbo-0613",
+		Choices: []gpt3.ChatCompletionResponseChoice{
+			{
+				Index:        0,
+				FinishReason: "stop",
+				Message:      gpt3.ChatCompletionResponseMessage{Role: "assistant", Content: "Roses are red"},
+			},
+			{
+				Index:        1,
+				FinishReason: "length",
+				Message:      gpt3.ChatCompletionResponseMessage{Role: "assistant", Content: "Violets"},
+			},
+		},
+		Usage: gpt3.Usage{PromptTokens: 9, CompletionTokens: 4, TotalTokens: 13},
+	}, resp)
+
+	// indexes the request can't have are errors rather than growing the choices
+	for _, tc := range []struct {
+		n        int
+		chunk    string
+		expected string
+	}{
+		{
+			0,
+			`{"choices":[{"index":0,"delta":{"content":"Roses"}},{"index":1,"delta":{"content":"Violets"}}]}`,
+			"choice index 1 is out of range for 1 choices",
+		},
+		{2, `{"choices":[{"index":2,"delta":{"content":"Roses"}}]}`, "choice index 2 is out of range for 2 choices"},
+	} {
+		rt.RoundTripReturns(fakeStreamResponse(tc.chunk, "[DONE]"), nil)
+		_, err = client.ChatCompletionStreamCollect(ctx, gpt3.ChatCompletionRequest{N: tc.n}, nil)
+		assert.EqualError(t, err, tc.expected)
+	}
+
+	rt.RoundTripReturns(fakeStreamResponse(`{"choices":[{"index":-1,"delta":{"content":"Roses"}}]}`, "[DONE]"), nil)
+	_, err = client.ChatCompletionStreamCollect(ctx, gpt3.ChatCompletionRequest{}, nil)
+	assert.EqualError(t, err, "choice index -1 is out of range for 1 choices")
+}
+
+func TestChatCompletionStreamCollectToolCalls(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"role":"assistant","content":null,"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"location\":"}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_weather","arguments":"{\"location\":\"Oslo\"}"}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		"[DONE]",
+	), nil)
+
+	resp, err := client.ChatCompletionStreamCollect(ctx, gpt3.ChatCompletionRequest{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []gpt3.ChatCompletionResponseChoice{{
+		Index:        0,
+		FinishReason: gpt3.FinishReasonToolCalls,
+		Message: gpt3.ChatCompletionResponseMessage{
+			Role: "assistant",
+			ToolCalls: []gpt3.ToolCall{
+				{
+					ID:       "call_1",
+					Type:     gpt3.ToolTypeFunction,
+					Function: gpt3.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+				},
+				{
+					ID:       "call_2",
+					Type:     gpt3.ToolTypeFunction,
+					Function: gpt3.FunctionCall{Name: "get_weather", Arguments: `{"location":"Oslo"}`},
+				},
+			},
+		},
+	}}, resp.Choices)
+}
+
+func TestCompletionStreamRejectsBestOf(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	request := gpt3.CompletionRequest{
+		Prompt: []string{"test"},
+		BestOf: gpt3.IntPtr(3),
+	}
+	err := client.CompletionStream(ctx, request, func(*gpt3.CompletionResponse) error { return nil })
+	assert.EqualError(t, err, "best_of can't be used when streaming completions")
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+}
+
+func TestCompletionLogProbs(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{
+			"id": "cmpl-123",
+			"object": "text_completion",
+			"created": 1589478378,
+			"model": "text-davinci-003",
+			"choices": [{
+				"text": " Paris.",
+				"index": 0,
+				"logprobs": {
+					"tokens": [" Paris", "."],
+					"token_logprobs": [-0.0151, -0.4932],
+					"top_logprobs": [{" Paris": -0.0151, " France": -4.3218}, {".": -0.4932, ",": -1.1047}],
+					"text_offset": [30, 36]
+				},
+				"finish_reason": "stop"
+			}]
+		}`)),
+	}, nil)
+
+	rsp, err := client.Completion(ctx, gpt3.CompletionRequest{
+		Prompt:   []string{"The capital of France is"},
+		LogProbs: gpt3.IntPtr(2),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &gpt3.LogProbResult{
+		Tokens:        []string{" Paris", "."},
+		TokenLogprobs: []float32{-0.0151, -0.4932},
+		TopLogprobs: []map[string]float32{
+			{" Paris": -0.0151, " France": -4.3218},
+			{".": -0.4932, ",": -1.1047},
+		},
+		TextOffset: []int{30, 36},
+	}, rsp.Choices[0].LogProbs)
+}
+
+func TestChatCompletionLogprobs(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{
+			"id": "chatcmpl-123",
+			"object": "chat.completion",
+			"choices": [{
+				"index": 0,
+				"message": {"role": "assistant", "content": "Yes"},
+				"logprobs": {
+					"content": [{
+						"token": "Yes",
+						"logprob": -0.0231,
+						"bytes": [89, 101, 115],
+						"top_logprobs": [
+							{"token": "Yes", "logprob": -0.0231, "bytes": [89, 101, 115]},
+							{"token": "No", "logprob": -3.7892, "bytes": [78, 111]}
+						]
+					}]
+				},
+				"finish_reason": "stop"
+			}]
+		}`)),
+	}, nil)
+
+	rsp, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{
+		Messages:    gpt3.NewMessages().User("Is the sky blue? Answer Yes or No").Build(),
+		Logprobs:    gpt3.BoolPtr(true),
+		TopLogprobs: gpt3.IntPtr(2),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &gpt3.ChatLogprobs{
+		Content: []gpt3.TokenLogprob{{
+			Token:   "Yes",
+			Logprob: -0.0231,
+			Bytes:   []int{89, 101, 115},
+			TopLogprobs: []gpt3.TopLogprob{
+				{Token: "Yes", Logprob: -0.0231, Bytes: []int{89, 101, 115}},
+				{Token: "No", Logprob: -3.7892, Bytes: []int{78, 111}},
+			},
+		}},
+	}, rsp.Choices[0].Logprobs)
+
+	body, err := ioutil.ReadAll(rt.RoundTripArgsForCall(0).Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"logprobs":true,"top_logprobs":2`)
+
+	data, err := json.Marshal(gpt3.ChatCompletionRequest{})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "logprobs")
+}
+
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"object":"list","data":[{"id":"gpt-4"}]}`)),
+	}, nil)
+	assert.NoError(t, client.Ping(ctx))
+	req := rt.RoundTripArgsForCall(0)
+	assert.Equal(t, "GET", req.Method)
+	assert.Equal(t, "Bearer test-key", req.Header.Get("Authorization"))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 401,
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`)),
+	}, nil)
+	err := client.Ping(ctx)
+	var authErr gpt3.AuthenticationError
+	assert.True(t, errors.As(err, &authErr))
+	assert.Equal(t, "Incorrect API key provided", authErr.Message)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+		return nil, req.Context().Err()
+	}
+	assert.True(t, errors.Is(client.Ping(cancelled), context.Canceled))
+}
+
+func TestChoicesByPrompt(t *testing.T) {
+	// 2 prompts with n of 2, returned out of order
+	rsp := &gpt3.CompletionResponse{
+		Choices: []gpt3.CompletionResponseChoice{
+			{Index: 2, Text: "b0"},
+			{Index: 0, Text: "a0"},
+			{Index: 3, Text: "b1"},
+			{Index: 1, Text: "a1"},
+		},
+	}
+	assert.Equal(t, [][]gpt3.CompletionResponseChoice{
+		{{Index: 0, Text: "a0"}, {Index: 1, Text: "a1"}},
+		{{Index: 2, Text: "b0"}, {Index: 3, Text: "b1"}},
+	}, rsp.ChoicesByPrompt(2))
+
+	assert.Len(t, rsp.ChoicesByPrompt(0), 4)
+	assert.Nil(t, (&gpt3.CompletionResponse{}).ChoicesByPrompt(2))
+}
+
+func TestEnginesResponseHelpers(t *testing.T) {
+	rsp := &gpt3.EnginesResponse{
+		Data: []gpt3.EngineObject{
+			{ID: "ada", Ready: true},
+			{ID: "babbage", Ready: false},
+			{ID: "davinci", Ready: true},
+		},
+	}
+	assert.Equal(t, []gpt3.EngineObject{{ID: "ada", Ready: true}, {ID: "davinci", Ready: true}}, rsp.Ready())
+
+	engine, ok := rsp.ByID("babbage")
+	assert.True(t, ok)
+	assert.Equal(t, &gpt3.EngineObject{ID: "babbage"}, engine)
+	_, ok = rsp.ByID("curie")
+	assert.False(t, ok)
+
+	assert.Nil(t, (&gpt3.EnginesResponse{}).Ready())
+}
+
+func TestModelsResponseByID(t *testing.T) {
+	rsp := &gpt3.ModelsResponse{Data: []gpt3.ModelObject{{ID: "gpt-3.5-turbo"}, {ID: "gpt-4", OwnedBy: "openai"}}}
+
+	model, ok := rsp.ByID("gpt-4")
+	assert.True(t, ok)
+	assert.Equal(t, "openai", model.OwnedBy)
+	_, ok = rsp.ByID("gpt-5")
+	assert.False(t, ok)
+}
+
+func TestModerationResponseAnyFlagged(t *testing.T) {
+	rsp := &gpt3.ModerationResponse{
+		Results: []gpt3.ModerationResult{{Flagged: false}, {Flagged: false}},
+	}
+	assert.False(t, rsp.AnyFlagged())
+
+	rsp.Results = append(rsp.Results, gpt3.ModerationResult{Flagged: true})
+	assert.True(t, rsp.AnyFlagged())
+}
+
+func TestCreateImageInvalidSize(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rsp, err := client.CreateImage(ctx, gpt3.ImageRequest{
+		Prompt: "a white siamese cat",
+		Size:   "300x300",
+	})
+	assert.Nil(t, rsp)
+	assert.EqualError(t, err, `invalid image size "300x300", must be one of 256x256, 512x512 or 1024x1024`)
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+}
+
+func TestCreateImageEditMultipart(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"created":1589478378,"data":[]}`)),
+	}, nil)
+
+	_, err := client.CreateImageEdit(ctx, gpt3.ImageEditRequest{
+		Image:  bytes.NewBufferString("image bytes"),
+		Mask:   bytes.NewBufferString("mask bytes"),
+		Prompt: "a sunlit indoor lounge area with a pool",
+		N:      gpt3.IntPtr(2),
+		Size:   gpt3.ImageSize512x512,
+	})
+	assert.NoError(t, err)
+
+	req := rt.RoundTripArgsForCall(0)
+	assert.True(t, strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data; boundary="))
+	assert.NoError(t, req.ParseMultipartForm(1<<20))
+	assert.Equal(t, []string{"a sunlit indoor lounge area with a pool"}, req.MultipartForm.Value["prompt"])
+	assert.Equal(t, []string{"2"}, req.MultipartForm.Value["n"])
+	assert.Equal(t, []string{"512x512"}, req.MultipartForm.Value["size"])
+	assert.NotContains(t, req.MultipartForm.Value, "response_format")
+
+	for name, expected := range map[string]string{"image": "image bytes", "mask": "mask bytes"} {
+		file, err := req.MultipartForm.File[name][0].Open()
+		assert.NoError(t, err)
+		data, err := ioutil.ReadAll(file)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+	}
+}
+
+func TestCreateImageVariationRequiresImage(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rsp, err := client.CreateImageVariation(ctx, gpt3.ImageVariationRequest{})
+	assert.Nil(t, rsp)
+	assert.EqualError(t, err, "an image is required")
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+}
+
+func TestCreateTranscription(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	t.Run("verbose json", func(t *testing.T) {
+		rt.RoundTripReturns(&http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{
+				"task": "transcribe",
+				"language": "english",
+				"duration": 2.95,
+				"text": "Hello there.",
+				"segments": [{"id": 0, "seek": 0, "start": 0.0, "end": 2.95, "text": " Hello there.", "tokens": [50364, 2425]}]
+			}`)),
+		}, nil)
+
+		rsp, err := client.CreateTranscription(ctx, gpt3.AudioRequest{
+			File:           bytes.NewBufferString("audio"),
+			FileName:       "hello.mp3",
+			ResponseFormat: gpt3.AudioResponseFormatVerboseJSON,
+			Temperature:    gpt3.Float32Ptr(0.2),
+			Language:       "en",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, &gpt3.AudioResponse{
+			Task:     "transcribe",
+			Language: "english",
+			Duration: 2.95,
+			Text:     "Hello there.",
+			Segments: []gpt3.AudioSegment{{End: 2.95, Text: " Hello there.", Tokens: []int{50364, 2425}}},
+		}, rsp)
+
+		req := rt.RoundTripArgsForCall(rt.RoundTripCallCount() - 1)
+		assert.NoError(t, req.ParseMultipartForm(1<<20))
+		assert.Equal(t, []string{gpt3.Whisper1}, req.MultipartForm.Value["model"])
+		assert.Equal(t, []string{"0.2"}, req.MultipartForm.Value["temperature"])
+		assert.Equal(t, []string{"en"}, req.MultipartForm.Value["language"])
+		assert.Equal(t, "hello.mp3", req.MultipartForm.File["file"][0].Filename)
+	})
+
+	t.Run("plain text formats", func(t *testing.T) {
+		srt := "1\n00:00:00,000 --> 00:00:02,950\nHello there.\n"
+		rt.RoundTripReturns(&http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(srt)),
+		}, nil)
+
+		rsp, err := client.CreateTranscription(ctx, gpt3.AudioRequest{
+			File:           bytes.NewBufferString("audio"),
+			FileName:       "hello.mp3",
+			ResponseFormat: gpt3.AudioResponseFormatSRT,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, srt, rsp.Text)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		rsp, err := client.CreateTranscription(ctx, gpt3.AudioRequest{File: bytes.NewBufferString("audio")})
+		assert.Nil(t, rsp)
+		assert.EqualError(t, err, "an audio file and file name are required")
+	})
+}
+
+// tinyWAV is a valid mono 8kHz 8-bit PCM wav file holding four samples of silence
+var tinyWAV = []byte{
+	'R', 'I', 'F', 'F', 40, 0, 0, 0, 'W', 'A', 'V', 'E',
+	'f', 'm', 't', ' ', 16, 0, 0, 0, 1, 0, 1, 0, 0x40, 0x1f, 0, 0, 0x40, 0x1f, 0, 0, 1, 0, 8, 0,
+	'd', 'a', 't', 'a', 4, 0, 0, 0, 0x80, 0x80, 0x80, 0x80,
+}
+
+func TestCreateTranslation(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{
+			"task": "translate",
+			"language": "german",
+			"duration": 0.5,
+			"text": "Hello.",
+			"segments": [{"id": 0, "start": 0.0, "end": 0.5, "text": " Hello."}]
+		}`)),
+	}, nil)
+
+	rsp, err := client.CreateTranslation(ctx, gpt3.AudioRequest{
+		File:           bytes.NewReader(tinyWAV),
+		FileName:       "hallo.wav",
+		Prompt:         "Greetings",
+		ResponseFormat: gpt3.AudioResponseFormatVerboseJSON,
+		Language:       "de",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello.", rsp.Text)
+	assert.Equal(t, []gpt3.AudioSegment{{End: 0.5, Text: " Hello."}}, rsp.Segments)
+
+	req := rt.RoundTripArgsForCall(0)
+	assert.Equal(t, "https://api.openai.com/v1/audio/translations", req.URL.String())
+	assert.NoError(t, req.ParseMultipartForm(1<<20))
+	assert.Equal(t, []string{gpt3.Whisper1}, req.MultipartForm.Value["model"])
+	assert.Equal(t, []string{"Greetings"}, req.MultipartForm.Value["prompt"])
+	assert.Equal(t, []string{gpt3.AudioResponseFormatVerboseJSON}, req.MultipartForm.Value["response_format"])
+	assert.NotContains(t, req.MultipartForm.Value, "language")
+
+	header := req.MultipartForm.File["file"][0]
+	assert.Equal(t, "hallo.wav", header.Filename)
+	file, err := header.Open()
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(file)
+	assert.NoError(t, err)
+	assert.Equal(t, tinyWAV, data)
+}
+
+func TestListFineTuneEvents(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	expected := []string{"Job enqueued.", "Job started.", "Job succeeded."}
+
+	t.Run("list", func(t *testing.T) {
+		rt.RoundTripReturns(&http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{"object":"list","data":[
+				{"object":"fine-tune-event","created_at":1,"level":"info","message":"Job enqueued."},
+				{"object":"fine-tune-event","created_at":2,"level":"info","message":"Job started."},
+				{"object":"fine-tune-event","created_at":3,"level":"info","message":"Job succeeded."}
+			]}`)),
+		}, nil)
+
+		var messages []string
+		err := client.ListFineTuneEvents(ctx, "ft-123", false, func(event *gpt3.FineTuneEvent) {
+			messages = append(messages, event.Message)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, messages)
+
+		req := rt.RoundTripArgsForCall(rt.RoundTripCallCount() - 1)
+		assert.Equal(t, "https://api.openai.com/v1/fine-tunes/ft-123/events", req.URL.String())
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		rt.RoundTripReturns(fakeStreamResponse(
+			`{"object":"fine-tune-event","created_at":1,"level":"info","message":"Job enqueued."}`,
+			`{"object":"fine-tune-event","created_at":2,"level":"info","message":"Job started."}`,
+			`{"object":"fine-tune-event","created_at":3,"level":"info","message":"Job succeeded."}`,
+			"[DONE]",
+		), nil)
+
+		var messages []string
+		err := client.ListFineTuneEvents(ctx, "ft-123", true, func(event *gpt3.FineTuneEvent) {
+			messages = append(messages, event.Message)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, messages)
+	})
+}
+
+func TestDeleteModelNotFound(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 404,
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"error":{"message":"The model 'curie:ft-missing' does not exist","type":"invalid_request_error"}}`)),
+	}, nil)
+
+	rsp, err := client.DeleteModel(ctx, "curie:ft-missing")
+	assert.Nil(t, rsp)
+
+	var apiErr gpt3.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 404, apiErr.StatusCode)
+	assert.Equal(t, "The model 'curie:ft-missing' does not exist", apiErr.Message)
+}
+
+func TestDeleteFileErrors(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	errorResponse := func(status int, message, code string) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Body: ioutil.NopCloser(bytes.NewBufferString(
+				`{"error":{"message":"` + message + `","type":"invalid_request_error","code":"` + code + `"}}`)),
+		}
+	}
+
+	rt.RoundTripReturns(errorResponse(404, "No such File object: file-123", ""), nil)
+	_, err := client.DeleteFile(ctx, "file-123")
+	assert.True(t, errors.Is(err, gpt3.ErrFileNotFound))
+	var apiErr gpt3.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "No such File object: file-123", apiErr.Message)
+
+	rt.RoundTripReturns(errorResponse(409, "File is still in use by fine-tune ft-456", ""), nil)
+	_, err = client.DeleteFile(ctx, "file-123")
+	var inUseErr gpt3.FileInUseError
+	assert.True(t, errors.As(err, &inUseErr))
+	assert.Equal(t, "File is still in use by fine-tune ft-456", inUseErr.Message)
+	assert.False(t, errors.Is(err, gpt3.ErrFileNotFound))
+
+	rt.RoundTripReturns(errorResponse(400, "Cannot delete a file used by a fine-tuning job", "file_in_use"), nil)
+	_, err = client.DeleteFile(ctx, "file-123")
+	assert.True(t, errors.As(err, &inUseErr))
+	var invalidErr gpt3.InvalidRequestError
+	assert.True(t, errors.As(err, &invalidErr))
+
+	// only the code tells a file in use apart from other invalid requests
+	rt.RoundTripReturns(errorResponse(400, "Invalid purpose for fine-tuning", ""), nil)
+	_, err = client.DeleteFile(ctx, "file-123")
+	assert.False(t, errors.As(err, &inUseErr))
+	assert.True(t, errors.As(err, &invalidErr))
+
+	rt.RoundTripReturns(errorResponse(500, "oops", ""), nil)
+	_, err = client.DeleteFile(ctx, "file-123")
+	var serverErr gpt3.ServerError
+	assert.True(t, errors.As(err, &serverErr))
+	assert.False(t, errors.Is(err, gpt3.ErrFileNotFound))
+}
+
+func TestClose(t *testing.T) {
+	ctx := context.Background()
+	closed := make(chan struct{}, 10)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"object":"list","data":[]}`)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+	_, err := client.Models(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Close())
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("the idle connection was not closed")
+	}
+	assert.NoError(t, client.Close())
+
+	// the client still works after closing
+	_, err = client.Models(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Close())
+	<-closed
+
+	// a custom http client is left alone
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	defer transport.CloseIdleConnections()
+	client = gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL), gpt3.WithHTTPClient(&http.Client{Transport: transport}))
+	_, err = client.Models(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Close())
+	select {
+	case <-closed:
+		t.Fatal("the idle connection of a custom http client was closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWithInsecureSkipVerify(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"object":"list","data":[]}`)
+	}))
+	defer server.Close()
+
+	// the test server's certificate is self-signed
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+	_, err := client.Models(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+
+	client = gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL), gpt3.WithInsecureSkipVerify())
+	_, err = client.Models(ctx)
+	assert.NoError(t, err)
+
+	// a custom http client is used as is
+	client = gpt3.NewClient("test-key",
+		gpt3.WithBaseURL(server.URL),
+		gpt3.WithInsecureSkipVerify(),
+		gpt3.WithHTTPClient(&http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}))
+	_, err = client.Models(ctx)
+	assert.Error(t, err)
+}
+
+func TestWithIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	keys := 0
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithRetry(1),
+		gpt3.WithIdempotencyKey(func() string {
+			keys++
+			return fmt.Sprintf("key-%d", keys)
+		}))
+
+	var sent []string
+	responses := []*http.Response{
+		{StatusCode: 500, Body: ioutil.NopCloser(bytes.NewBufferString(`{"error":{"message":"try again"}}`))},
+		{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"output"}]}`))},
+		{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"output"}]}`))},
+		{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(`{"data":[]}`))},
+	}
+	rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, req.Header.Get("Idempotency-Key"))
+		return responses[len(sent)-1], nil
+	}
+
+	// a retried call reuses its key, and each call gets a new one
+	_, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	_, err = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	// GET requests are idempotent already
+	_, err = client.Models(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key-1", "key-1", "key-2", ""}, sent)
+
+	t.Run("random keys", func(t *testing.T) {
+		rt, httpClient := fakeHttpClient()
+		client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), gpt3.WithIdempotencyKey(nil))
+		rt.RoundTripReturns(nil, errors.New("request error"))
+
+		_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		first := rt.RoundTripArgsForCall(0).Header.Get("Idempotency-Key")
+		assert.Len(t, first, 32)
+		assert.NotEqual(t, first, rt.RoundTripArgsForCall(1).Header.Get("Idempotency-Key"))
+	})
+}
+
+func TestWithCache(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), gpt3.WithCache(gpt3.NewLRUCache(10)))
+	rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/chat/completions") {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"message":{"role":"assistant","content":"Hi"}}]}`)),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"output"}]}`)),
+		}, nil
+	}
+
+	deterministic := gpt3.CompletionRequest{Prompt: []string{"test"}, Temperature: gpt3.Float32Ptr(0)}
+	for i := 0; i < 2; i++ {
+		rsp, err := client.Completion(ctx, deterministic)
+		assert.NoError(t, err)
+		assert.Equal(t, "output", rsp.Choices[0].Text)
+	}
+	assert.Equal(t, 1, rt.RoundTripCallCount())
+
+	// a different request isn't served from the cache
+	_, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"other"}, Temperature: gpt3.Float32Ptr(0)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rt.RoundTripCallCount())
+
+	// nor is one that samples randomly
+	random := gpt3.CompletionRequest{Prompt: []string{"test"}, Temperature: gpt3.Float32Ptr(0.7)}
+	for i := 0; i < 2; i++ {
+		_, err := client.Completion(ctx, random)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 4, rt.RoundTripCallCount())
+
+	seeded := gpt3.ChatCompletionRequest{Messages: gpt3.NewMessages().User("Hello").Build(), Seed: gpt3.IntPtr(42)}
+	for i := 0; i < 2; i++ {
+		rsp, err := client.ChatCompletion(ctx, seeded)
+		assert.NoError(t, err)
+		assert.Equal(t, "Hi", rsp.Choices[0].Message.Content)
+	}
+	assert.Equal(t, 5, rt.RoundTripCallCount())
+
+	unseeded := gpt3.ChatCompletionRequest{Messages: gpt3.NewMessages().User("Hello").Build()}
+	for i := 0; i < 2; i++ {
+		_, err := client.ChatCompletion(ctx, unseeded)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 7, rt.RoundTripCallCount())
+
+	// the same request for another organization isn't served the response of the first
+	orgCtx := gpt3.WithRequestOrg(ctx, "org-other")
+	for i := 0; i < 2; i++ {
+		_, err := client.ChatCompletion(orgCtx, seeded)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 8, rt.RoundTripCallCount())
+
+	// nor for another project sharing the cache
+	cache := gpt3.NewLRUCache(10)
+	client = gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), gpt3.WithCache(cache))
+	_, err = client.ChatCompletion(ctx, seeded)
+	assert.NoError(t, err)
+	projectClient := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), gpt3.WithProject("proj-1"),
+		gpt3.WithCache(cache))
+	_, err = projectClient.ChatCompletion(ctx, seeded)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, rt.RoundTripCallCount())
+
+	// empty responses aren't cached
+	rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"message":{"role":"assistant","content":""}}]}`)),
+		}, nil
+	}
+	empty := gpt3.ChatCompletionRequest{Messages: gpt3.NewMessages().User("Empty").Build(), Seed: gpt3.IntPtr(42)}
+	for i := 0; i < 2; i++ {
+		_, err := client.ChatCompletion(ctx, empty)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 12, rt.RoundTripCallCount())
+}
+
+func TestWithRetryOnEmpty(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), gpt3.WithRetryOnEmpty(2))
+
+	var bodies []string
+	replyWith := func(contents ...string) {
+		bodies = nil
+		rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+			data, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			bodies = append(bodies, string(data))
+			return &http.Response{
+				StatusCode: 200,
+				Body: ioutil.NopCloser(bytes.NewBufferString(fmt.Sprintf(
+					`{"choices":[{"message":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`,
+					contents[len(bodies)-1]))),
+			}, nil
+		}
+	}
+
+	t.Run("retries with a new seed", func(t *testing.T) {
+		replyWith("", "", "hello")
+		rsp, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{Seed: gpt3.IntPtr(7)})
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", rsp.Choices[0].Message.Content)
+		assert.Len(t, bodies, 3)
+		for i, body := range bodies {
+			assert.Contains(t, body, fmt.Sprintf(`"seed":%d`, 7+i))
+		}
+	})
+
+	t.Run("raises the temperature without a seed", func(t *testing.T) {
+		replyWith("", "hello")
+		_, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{Temperature: 0.5})
+		assert.NoError(t, err)
+		assert.Len(t, bodies, 2)
+		assert.Contains(t, bodies[0], `"temperature":0.5`)
+		assert.Contains(t, bodies[1], `"temperature":0.6`)
+	})
+
+	t.Run("returns the empty response after n retries", func(t *testing.T) {
+		replyWith("", "", "")
+		rsp, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "", rsp.Choices[0].Message.Content)
+		assert.Len(t, bodies, 3)
+		assert.NotContains(t, bodies[0], "temperature")
+		assert.Contains(t, bodies[1], `"temperature":1.1`)
+		assert.Contains(t, bodies[2], `"temperature":1.2`)
+	})
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithRetry(2),
+		gpt3.WithBackoffStrategy(func(int) time.Duration { return 0 }))
+
+	errorResponse := func(code int) *http.Response {
+		return &http.Response{
+			StatusCode: code,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"error":{"message":"try again","type":"server_error"}}`)),
+		}
+	}
+
+	// records the request bodies and replies with the given responses in order
+	replyWith := func(responses ...*http.Response) *[]string {
+		var bodies []string
+		rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+			data, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			bodies = append(bodies, string(data))
+			return responses[len(bodies)-1], nil
+		}
+		return &bodies
+	}
+
+	t.Run("retries until success", func(t *testing.T) {
+		bodies := replyWith(errorResponse(503), errorResponse(429), &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"output"}]}`)),
+		})
+
+		rsp, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "output", rsp.Choices[0].Text)
+		assert.Len(t, *bodies, 3)
+		for _, body := range *bodies {
+			assert.Contains(t, body, `"prompt":["test"]`)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		bodies := replyWith(errorResponse(500), errorResponse(502), errorResponse(503))
+
+		rsp, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		assert.Nil(t, rsp)
+		assert.EqualError(t, err, "[503:server_error] try again")
+		assert.Len(t, *bodies, 3)
+	})
+
+	t.Run("waits for retry-after", func(t *testing.T) {
+		// Retry-After is in whole seconds, so rather than waiting it out the deadline ends the wait before the retry
+		rateLimited := errorResponse(429)
+		rateLimited.Header = http.Header{"Retry-After": []string{"1"}}
+		bodies := replyWith(rateLimited, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"output"}]}`)),
+		})
+		shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.Completion(shortCtx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+		assert.Len(t, *bodies, 1)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		bodies := replyWith(errorResponse(400))
+
+		_, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		assert.EqualError(t, err, "[400:server_error] try again")
+		assert.Len(t, *bodies, 1)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		bodies := replyWith(errorResponse(503), errorResponse(503), errorResponse(503))
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := client.Completion(cancelled, gpt3.CompletionRequest{Prompt: []string{"test"}})
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Len(t, *bodies, 1)
+	})
+}
+
+func TestWithBackoffStrategy(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+
+	var attempts []int
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithRetry(3),
+		gpt3.WithBackoffStrategy(func(attempt int) time.Duration {
+			attempts = append(attempts, attempt)
+			return -time.Second
+		}))
+
+	calls := 0
+	rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls <= 3 {
+			return &http.Response{
+				StatusCode: 503,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"error":{"message":"try again","type":"server_error"}}`)),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"choices":[{"text":"output"}]}`)),
+		}, nil
+	}
+
+	start := time.Now()
+	rsp, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "output", rsp.Choices[0].Text)
+	assert.Equal(t, []int{0, 1, 2}, attempts)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	for attempt, max := range []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second} {
+		delay := gpt3.DefaultBackoff(attempt)
+		assert.GreaterOrEqual(t, int64(delay), int64(max/2))
+		assert.LessOrEqual(t, int64(delay), int64(max))
+	}
+	assert.LessOrEqual(t, int64(gpt3.DefaultBackoff(100)), int64(30*time.Second))
+}
+
+func TestRateLimitError(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	for _, tc := range []struct {
+		name       string
+		retryAfter string
+		expected   time.Duration
+	}{
+		{"Seconds", "120", 2 * time.Minute},
+		{"Past date", "Wed, 21 Oct 2015 07:28:00 GMT", 0},
+		{"Missing", "", 0},
+		{"Invalid", "soon", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rt.RoundTripReturns(&http.Response{
+				StatusCode: 429,
+				Header:     http.Header{"Retry-After": []string{tc.retryAfter}},
+				Body: ioutil.NopCloser(bytes.NewBufferString(
+					`{"error":{"message":"Rate limit reached","type":"requests"}}`)),
+			}, nil)
+
+			_, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+			assert.EqualError(t, err, "[429:requests] Rate limit reached")
+
+			var rateLimitErr gpt3.RateLimitError
+			assert.True(t, errors.As(err, &rateLimitErr))
+			assert.Equal(t, tc.expected, rateLimitErr.RetryAfter)
+
+			var apiErr gpt3.APIError
+			assert.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, 429, apiErr.StatusCode)
+		})
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	for _, tc := range []struct {
+		code    int
+		matches func(err error) bool
+	}{
+		{400, func(err error) bool { var target gpt3.InvalidRequestError; return errors.As(err, &target) }},
+		{401, func(err error) bool { var target gpt3.AuthenticationError; return errors.As(err, &target) }},
+		{403, func(err error) bool { var target gpt3.PermissionError; return errors.As(err, &target) }},
+		{429, func(err error) bool { var target gpt3.RateLimitError; return errors.As(err, &target) }},
+		{500, func(err error) bool { var target gpt3.ServerError; return errors.As(err, &target) }},
+		{503, func(err error) bool { var target gpt3.ServerError; return errors.As(err, &target) }},
+		{404, func(err error) bool { _, ok := err.(gpt3.APIError); return ok }},
+	} {
+		t.Run(fmt.Sprint(tc.code), func(t *testing.T) {
+			rt.RoundTripReturns(&http.Response{
+				StatusCode: tc.code,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"error":{"message":"failed","type":"test_type"}}`)),
+			}, nil)
+
+			_, err := client.Models(ctx)
+			assert.True(t, tc.matches(err), "unexpected error type %T", err)
+			assert.EqualError(t, err, fmt.Sprintf("[%d:test_type] failed", tc.code))
+		})
+	}
+}
+
+func TestAPIErrorDetails(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 400,
+		Header:     http.Header{"X-Request-Id": []string{"req_7f3a9c"}},
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{
+			"error": {
+				"message": "Invalid value for 'temperature'",
+				"type": "invalid_request_error",
+				"param": "temperature",
+				"code": "invalid_value"
+			}
+		}`)),
+	}, nil)
+
+	_, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	var apiErr gpt3.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, gpt3.APIError{
+		StatusCode: 400,
+		Message:    "Invalid value for 'temperature'",
+		Type:       "invalid_request_error",
+		Param:      "temperature",
+		Code:       "invalid_value",
+		RequestID:  "req_7f3a9c",
+	}, apiErr)
+	assert.EqualError(t, err, "[400:invalid_request_error] Invalid value for 'temperature' (code invalid_value, request id req_7f3a9c)")
+
+	// param and code are null for some errors
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 500,
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"error":{"message":"server error","type":"server_error","param":null,"code":null}}`)),
+	}, nil)
+	_, err = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, gpt3.APIError{StatusCode: 500, Message: "server error", Type: "server_error"}, apiErr)
+}
+
+func TestAPIErrorString(t *testing.T) {
+	type testCase struct {
+		err      gpt3.APIError
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			gpt3.APIError{StatusCode: 401, Type: "invalid_request_error", Message: "Incorrect API key provided"},
+			"[401:invalid_request_error] Incorrect API key provided",
+		},
+		{
+			gpt3.APIError{
+				StatusCode: 401,
+				Type:       "invalid_request_error",
+				Message:    "Incorrect API key provided",
+				Code:       "invalid_api_key",
+			},
+			"[401:invalid_request_error] Incorrect API key provided (code invalid_api_key)",
+		},
+		{
+			gpt3.APIError{StatusCode: 500, Type: "server_error", Message: "failed", RequestID: "req_1"},
+			"[500:server_error] failed (request id req_1)",
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.EqualError(t, tc.err, tc.expected)
+	}
+
+	var apiErr gpt3.APIErrorResponse
+	assert.NoError(t, json.Unmarshal([]byte(`{"error":{"message":"Incorrect API key provided",
+		"type":"invalid_request_error","param":null,"code":"invalid_api_key"}}`), &apiErr))
+	assert.Equal(t, "invalid_api_key", apiErr.Error.Code)
+}
+
+func TestCompletionStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	// the pipe is never closed by the writer, so reads block like a hung stream would
+	body, writer := io.Pipe()
+	rt.RoundTripReturns(&http.Response{StatusCode: 200, Body: body}, nil)
+	go writer.Write([]byte("data: {\"choices\":[{\"text\":\"Hello\"}]}\n\n"))
+
+	received := make(chan struct{}, 1)
+	done := make(chan error)
+	go func() {
+		done <- client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(*gpt3.CompletionResponse) error {
+			received <- struct{}{}
+			return nil
+		})
+	}()
+
+	// cancel while the stream is blocked waiting for the next chunk
+	<-received
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("CompletionStream did not return after the context was cancelled")
+	}
+}
+
+// trickleServer starts a server that streams the given chunk every 20ms until the client goes away
+func trickleServer(chunk string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}))
+}
+
+// assertStopsOnCancel calls stream with a context that is cancelled once the first chunk has been received, and
+// asserts that stream returns the context error promptly without leaving any goroutines behind.
+func assertStopsOnCancel(t *testing.T, chunk string, stream func(ctx context.Context, client gpt3.Client, received func()) error) {
+	server := trickleServer(chunk)
+	defer server.Close()
+	transport := &http.Transport{}
+	defer transport.CloseIdleConnections()
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL), gpt3.WithHTTPClient(&http.Client{Transport: transport}))
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var once sync.Once
+	done := make(chan error, 1)
+	go func() {
+		done <- stream(ctx, client, func() { once.Do(cancel) })
+	}()
+
+	select {
+	case err := <-done:
+		assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("stream did not return after the context was cancelled")
+	}
+
+	// the connection of the stream is closed in the background, so allow a moment for its goroutines to exit
+	transport.CloseIdleConnections()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "goroutines leaked")
+}
+
+func TestStreamsStopOnCancel(t *testing.T) {
+	completionChunk := `{"choices":[{"text":"Hello","index":0}]}`
+	chatChunk := `{"choices":[{"delta":{"content":"Hello"},"index":0}]}`
+
+	t.Run("CompletionStream", func(t *testing.T) {
+		assertStopsOnCancel(t, completionChunk, func(ctx context.Context, client gpt3.Client, received func()) error {
+			return client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(*gpt3.CompletionResponse) error {
+				received()
+				return nil
+			})
+		})
+	})
+
+	t.Run("CompletionStreamReader", func(t *testing.T) {
+		assertStopsOnCancel(t, completionChunk, func(ctx context.Context, client gpt3.Client, received func()) error {
+			stream, err := client.CompletionStreamReader(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+			if err != nil {
+				return err
+			}
+			defer stream.Close()
+			for {
+				if _, err := stream.Recv(); err != nil {
+					return err
+				}
+				received()
+			}
+		})
+	})
+
+	t.Run("ChatCompletionStream", func(t *testing.T) {
+		assertStopsOnCancel(t, chatChunk, func(ctx context.Context, client gpt3.Client, received func()) error {
+			err := client.ChatCompletionStream(ctx, gpt3.ChatCompletionRequest{}, func(*gpt3.ChatCompletionStreamResponse) {
+				received()
+			})
+			return err
+		})
+	})
+
+	t.Run("ChatCompletionStreamCollect", func(t *testing.T) {
+		assertStopsOnCancel(t, chatChunk, func(ctx context.Context, client gpt3.Client, received func()) error {
+			_, err := client.ChatCompletionStreamCollect(ctx, gpt3.ChatCompletionRequest{}, func(string) {
+				received()
+			})
+			return err
+		})
+	})
+
+	t.Run("ListFineTuneEvents", func(t *testing.T) {
+		assertStopsOnCancel(t, `{"object":"fine-tune-event","message":"running"}`,
+			func(ctx context.Context, client gpt3.Client, received func()) error {
+				return client.ListFineTuneEvents(ctx, "ft-123", true, func(*gpt3.FineTuneEvent) {
+					received()
+				})
+			})
+	})
+}
+
+func TestStreamStopsOnDeadline(t *testing.T) {
+	server := trickleServer(`{"choices":[{"text":"Hello","index":0}]}`)
+	defer server.Close()
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	chunks := 0
+	err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(*gpt3.CompletionResponse) error {
+		chunks++
+		return nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Greater(t, chunks, 0)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestCompletionStreamEOF(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	t.Run("without done after data", func(t *testing.T) {
+		rt.RoundTripReturns(&http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(bytes.NewBufferString(
+				"data: {\"choices\":[{\"text\":\"Hello\"}]}\n\ndata: {\"choices\":[{\"text\":\" world\"}]}")),
+		}, nil)
+
+		var text string
+		err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(rsp *gpt3.CompletionResponse) error {
+			text += rsp.Choices[0].Text
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello world", text)
+	})
+
+	t.Run("before any data", func(t *testing.T) {
+		rt.RoundTripReturns(&http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString("")),
+		}, nil)
+
+		err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(*gpt3.CompletionResponse) error {
+			t.Fatal("onData should not be called")
+			return nil
+		})
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+}
+
+func TestCompletionStreamAbort(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	body := &closeRecorder{Reader: bytes.NewBufferString(
+		"data: {\"choices\":[{\"text\":\"one\"}]}\n\n" +
+			"data: {\"choices\":[{\"text\":\"two\"}]}\n\n" +
+			"data: {\"choices\":[{\"text\":\"three\"}]}\n\n" +
+			"data: [DONE]\n\n")}
+	rt.RoundTripReturns(&http.Response{StatusCode: 200, Body: body}, nil)
+
+	errEnough := errors.New("seen enough")
+	var chunks []string
+	err := client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(rsp *gpt3.CompletionResponse) error {
+		chunks = append(chunks, rsp.Choices[0].Text)
+		if len(chunks) == 2 {
+			return errEnough
+		}
+		return nil
+	})
+	assert.Equal(t, errEnough, err)
+	assert.Equal(t, []string{"one", "two"}, chunks)
+	assert.True(t, body.closed)
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRequestNMarshaling(t *testing.T) {
+	data, err := json.Marshal(gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), `"n"`)
+
+	data, err = json.Marshal(gpt3.CompletionRequest{Prompt: []string{"test"}, N: gpt3.IntPtr(2)})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"n":2`)
+
+	data, err = json.Marshal(gpt3.EditsRequest{Input: "test"})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), `"n"`)
+}
+
+func gzipResponse(t *testing.T, statusCode int, body string) *http.Response {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(body))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:       ioutil.NopCloser(&buf),
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	respondWith := func(body string) {
+		rt.RoundTripReturns(&http.Response{
+			StatusCode: 200,
+			Header: http.Header{
+				"X-Ratelimit-Remaining-Requests": []string{"59"},
+				"X-Ratelimit-Reset-Tokens":       []string{"6ms"},
+			},
+			Body: ioutil.NopCloser(bytes.NewBufferString(body)),
+		}, nil)
+	}
+
+	respondWith(`{"choices":[{"text":"output"}]}`)
+	completion, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "59", completion.Header.Get("x-ratelimit-remaining-requests"))
+	assert.Equal(t, "6ms", completion.Header.Get("x-ratelimit-reset-tokens"))
+
+	respondWith(`{"choices":[{"message":{"role":"assistant","content":"output"}}]}`)
+	chat, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "59", chat.Header.Get("x-ratelimit-remaining-requests"))
+
+	respondWith(`{"data":[{"id":"davinci"}]}`)
+	models, err := client.Models(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "59", models.Header.Get("x-ratelimit-remaining-requests"))
+	assert.Equal(t, gpt3.ModelObject{ID: "davinci"}, models.Data[0])
+
+	respondWith("hello")
+	audio, err := client.CreateTranscription(ctx, gpt3.AudioRequest{
+		File:           bytes.NewBufferString("audio"),
+		FileName:       "audio.mp3",
+		ResponseFormat: gpt3.AudioResponseFormatText,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "59", audio.Header.Get("x-ratelimit-remaining-requests"))
+
+	// headers aren't part of the json
+	data, err := json.Marshal(completion)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "59")
+}
+
+func TestGzipResponses(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	rt.RoundTripReturns(gzipResponse(t, 200, `{"object":"list","data":[{"id":"davinci","object":"model"}]}`), nil)
+	models, err := client.Models(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "davinci", models.Data[0].ID)
+	assert.Equal(t, "gzip", rt.RoundTripArgsForCall(0).Header.Get("Accept-Encoding"))
+
+	rt.RoundTripReturns(gzipResponse(t, 200, "data: {\"choices\":[{\"text\":\"Hello\"}]}\n\ndata: [DONE]\n\n"), nil)
+	var text string
+	err = client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(rsp *gpt3.CompletionResponse) error {
+		text += rsp.Choices[0].Text
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+
+	rt.RoundTripReturns(gzipResponse(t, 400, `{"error":{"message":"bad request","type":"invalid_request_error"}}`), nil)
+	_, err = client.Models(ctx)
+	assert.EqualError(t, err, "[400:invalid_request_error] bad request")
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:       ioutil.NopCloser(bytes.NewBufferString("this is not gzip data")),
+	}, nil)
+	_, err = client.Models(ctx)
+	assert.EqualError(t, err, "invalid gzip response: gzip: invalid header")
+}
+
+func TestWithBaseURL(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithBaseURL("https://gateway.example.com/openai/"))
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	_, err := client.Models(ctx)
+	assert.Error(t, err)
+	_, err = client.CompletionWithEngine(ctx, "davinci", gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.Error(t, err)
+
+	// every engine scoped endpoint is built from the client's base URL
+	_, err = client.Engine(ctx, "davinci")
+	assert.Error(t, err)
+	err = client.CompletionStreamWithEngine(ctx, "davinci", gpt3.CompletionRequest{Prompt: []string{"test"}},
+		func(*gpt3.CompletionResponse) error { return nil })
+	assert.Error(t, err)
+	_, err = client.SearchWithEngine(ctx, "davinci", gpt3.SearchRequest{Documents: []string{"test"}})
+	assert.Error(t, err)
+
+	assert.Equal(t, 5, rt.RoundTripCallCount())
+	assert.Equal(t, "https://gateway.example.com/openai/models", rt.RoundTripArgsForCall(0).URL.String())
+	for i, path := range []string{
+		"/engines/davinci/completions",
+		"/engines/davinci",
+		"/engines/davinci/completions",
+		"/engines/davinci/search",
+	} {
+		assert.Equal(t, "https://gateway.example.com/openai"+path, rt.RoundTripArgsForCall(i+1).URL.String())
+	}
+}
+
+func TestWithHTTPClientKeepsTimeout(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]gpt3.ClientOption{
+		{gpt3.WithTimeout(time.Second)},
+		{},
+	} {
+		rt, httpClient := fakeHttpClient()
+		httpClient.Timeout = 5 * time.Minute
+		client := gpt3.NewClient("test-key", append(opts, gpt3.WithHTTPClient(httpClient))...)
+
+		rt.RoundTripReturns(nil, errors.New("request error"))
+		_, err := client.Models(ctx)
+		assert.Error(t, err)
+		assert.Equal(t, 1, rt.RoundTripCallCount())
+		assert.Equal(t, 5*time.Minute, httpClient.Timeout)
+	}
+}
+
+func TestTimeoutDefersToContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		fmt.Fprint(w, `{"object":"list","data":[]}`)
+	}))
+	defer server.Close()
+
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL), gpt3.WithTimeout(50*time.Millisecond))
+
+	// without a deadline the client timeout applies
+	_, err := client.Models(context.Background())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	// a context deadline replaces the client timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = client.Models(ctx)
+	assert.NoError(t, err)
+}
+
+func TestTimeoutDoesNotLimitStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "data: {\"id\":\"%d\"}\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := gpt3.NewClient("test-key", gpt3.WithBaseURL(server.URL), gpt3.WithTimeout(100*time.Millisecond))
+
+	var ids []string
+	err := client.CompletionStream(context.Background(), gpt3.CompletionRequest{Prompt: []string{"test"}}, func(resp *gpt3.CompletionResponse) error {
+		ids = append(ids, resp.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0", "1", "2", "3"}, ids)
+}
+
+func TestWithAzure(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithAzure("my-resource", "my-deployment", "2023-05-15"))
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	_, _ = client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{})
+	_, _ = client.ListFiles(ctx)
+	_ = client.ListFineTuneEvents(ctx, "ft-123", true, func(*gpt3.FineTuneEvent) {})
+
+	assert.Equal(t, 4, rt.RoundTripCallCount())
+	expected := []string{
+		"https://my-resource.openai.azure.com/openai/deployments/my-deployment/completions?api-version=2023-05-15",
+		"https://my-resource.openai.azure.com/openai/deployments/my-deployment/chat/completions?api-version=2023-05-15",
+		"https://my-resource.openai.azure.com/openai/files?api-version=2023-05-15",
+		"https://my-resource.openai.azure.com/openai/fine-tunes/ft-123/events?api-version=2023-05-15&stream=true",
+	}
+	for i, url := range expected {
+		req := rt.RoundTripArgsForCall(i)
+		assert.Equal(t, url, req.URL.String())
+		assert.Equal(t, "test-key", req.Header.Get("api-key"))
+		assert.Empty(t, req.Header.Get("Authorization"))
+	}
+}
+
+func TestWithHeaders(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithHeader("X-Gateway-Token", "secret"),
+		gpt3.WithHeaders(http.Header{"X-Trace": []string{"a", "b"}}))
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	_, _ = client.Models(ctx)
+	_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+
+	assert.Equal(t, 2, rt.RoundTripCallCount())
+	for i := 0; i < 2; i++ {
+		req := rt.RoundTripArgsForCall(i)
+		assert.Equal(t, "secret", req.Header.Get("X-Gateway-Token"))
+		assert.Equal(t, []string{"a", "b"}, req.Header.Values("X-Trace"))
+		assert.Equal(t, "Bearer test-key", req.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	}
+}
+
+func TestWithHeaderOverridesDefaults(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithHeader("Authorization", "Gateway other-key"))
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	_, _ = client.Models(ctx)
+	assert.Equal(t, 1, rt.RoundTripCallCount())
+	assert.Equal(t, "Gateway other-key", rt.RoundTripArgsForCall(0).Header.Get("Authorization"))
+}
+
+func TestWithOrgAndProject(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithOrg("org-123"),
+		gpt3.WithProject("proj-456"))
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	_, _ = client.Models(ctx)
+	assert.Equal(t, 1, rt.RoundTripCallCount())
+	req := rt.RoundTripArgsForCall(0)
+	assert.Equal(t, "org-123", req.Header.Get("OpenAI-Organization"))
+	assert.Equal(t, "proj-456", req.Header.Get("OpenAI-Project"))
+}
+
+func TestWithRequestOrg(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithOrg("org-123"))
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	_, _ = client.Models(gpt3.WithRequestOrg(ctx, "org-789"))
+	_, _ = client.Models(ctx)
+	assert.Equal(t, 2, rt.RoundTripCallCount())
+	assert.Equal(t, "org-789", rt.RoundTripArgsForCall(0).Header.Get("OpenAI-Organization"))
+	assert.Equal(t, "org-123", rt.RoundTripArgsForCall(1).Header.Get("OpenAI-Organization"))
+}
+
+func TestWithUserIDHasher(t *testing.T) {
+	type userKey struct{}
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithUserIDHasher(func(ctx context.Context) string {
+			user, _ := ctx.Value(userKey{}).(string)
+			return "hashed-" + user
+		}))
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	sentUser := func(call int) string {
+		var body struct {
+			User string `json:"user"`
+		}
+		assert.NoError(t, json.NewDecoder(rt.RoundTripArgsForCall(call).Body).Decode(&body))
+		return body.User
+	}
+
+	_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.Equal(t, "hashed-alice", sentUser(0))
+
+	_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}, User: "bob"})
+	assert.Equal(t, "bob", sentUser(1))
+
+	_ = client.ChatCompletionStream(ctx, gpt3.ChatCompletionRequest{}, func(*gpt3.ChatCompletionStreamResponse) {})
+	assert.Equal(t, "hashed-alice", sentUser(2))
+
+	_, _ = client.Embeddings(ctx, gpt3.EmbeddingsRequest{Input: []string{"test"}})
+	assert.Equal(t, "hashed-alice", sentUser(3))
+}
+
+func TestWithDefaultEngineAndModel(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key",
+		gpt3.WithHTTPClient(httpClient),
+		gpt3.WithDefaultEngine(gpt3.CurieEngine),
+		gpt3.WithDefaultModel(gpt3.GPT3Dot5Turbo0301))
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+
+	_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	_, _ = client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{})
+	_, _ = client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{Model: gpt3.GPT3Dot5Turbo})
+
+	assert.Equal(t, 3, rt.RoundTripCallCount())
+	assert.Equal(t, "/v1/engines/curie/completions", rt.RoundTripArgsForCall(0).URL.Path)
+
+	for i, model := range []string{gpt3.GPT3Dot5Turbo0301, gpt3.GPT3Dot5Turbo} {
+		var body gpt3.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(rt.RoundTripArgsForCall(i+1).Body).Decode(&body))
+		assert.Equal(t, model, body.Model)
+	}
+}
+
+func TestChatCompletionResponseFormat(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	jsonMode := &gpt3.ResponseFormat{Type: gpt3.ResponseFormatTypeJSONObject}
+	_, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{
+		Messages:       []gpt3.ChatCompletionRequestMessage{{Role: "user", Content: "List three colors"}},
+		ResponseFormat: jsonMode,
+	})
+	assert.EqualError(t, err, "json_object response format requires a message that mentions JSON")
+	err = client.ChatCompletionStream(ctx, gpt3.ChatCompletionRequest{
+		Messages:       []gpt3.ChatCompletionRequestMessage{{Role: "user", Content: "List three colors"}},
+		ResponseFormat: jsonMode,
+	}, func(*gpt3.ChatCompletionStreamResponse) {})
+	assert.EqualError(t, err, "json_object response format requires a message that mentions JSON")
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+	_, _ = client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{
+		Messages: []gpt3.ChatCompletionRequestMessage{
+			{Role: "system", Content: "Reply in Json"},
+			{Role: "user", Content: "List three colors"},
+		},
+		ResponseFormat: jsonMode,
+	})
+	_, _ = client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{})
+	assert.Equal(t, 2, rt.RoundTripCallCount())
+
+	body, err := ioutil.ReadAll(rt.RoundTripArgsForCall(0).Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"response_format":{"type":"json_object"}`)
+	body, err = ioutil.ReadAll(rt.RoundTripArgsForCall(1).Body)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "response_format")
+}
+
+func TestChatCompletionTools(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	mockResponse := &http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(strings.NewReader(`{"choices":[{"index":0,"finish_reason":"tool_calls",` +
+			`"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function",` +
+			`"function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]}}]}`)),
+	}
+	rt.RoundTripReturns(mockResponse, nil)
+
+	resp, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{
+		Messages: []gpt3.ChatCompletionRequestMessage{{Role: "user", Content: "What's the weather in Paris?"}},
+		Tools: []gpt3.Tool{{
+			Type: gpt3.ToolTypeFunction,
+			Function: gpt3.FunctionDefinition{
+				Name:       "get_weather",
+				Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+			},
+		}},
+		ToolChoice: "auto",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, gpt3.FinishReasonToolCalls, resp.Choices[0].FinishReason)
+	assert.Equal(t, []gpt3.ToolCall{{
+		ID:       "call_1",
+		Type:     gpt3.ToolTypeFunction,
+		Function: gpt3.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+	}}, resp.Choices[0].Message.ToolCalls)
+
+	body, err := ioutil.ReadAll(rt.RoundTripArgsForCall(0).Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"tools":[{"type":"function","function":{"name":"get_weather",`+
+		`"parameters":{"type":"object","properties":{"city":{"type":"string"}}}}}],"tool_choice":"auto"`)
+
+	data, err := json.Marshal(gpt3.ChatCompletionRequest{Messages: []gpt3.ChatCompletionRequestMessage{{Role: "user"}}})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "tool")
+}
+
+func TestSeedMarshaling(t *testing.T) {
+	data, err := json.Marshal(gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "seed")
+
+	data, err = json.Marshal(gpt3.CompletionRequest{Prompt: []string{"test"}, Seed: gpt3.IntPtr(42)})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"seed":42`)
+
+	data, err = json.Marshal(gpt3.ChatCompletionRequest{})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "seed")
+
+	data, err = json.Marshal(gpt3.ChatCompletionRequest{Seed: gpt3.IntPtr(0)})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"seed":0`)
+
+	var resp gpt3.ChatCompletionResponse
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":"123","system_fingerprint":"fp_44709d6fcb"}`), &resp))
+	assert.Equal(t, "fp_44709d6fcb", resp.SystemFingerprint)
+}
+
+func TestCompletionPenalties(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	_, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}, PresencePenalty: gpt3.Float32Ptr(2.5)})
+	assert.EqualError(t, err, "presence_penalty must be between -2.0 and 2.0, got 2.5")
+	err = client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}, FrequencyPenalty: gpt3.Float32Ptr(-3)},
+		func(*gpt3.CompletionResponse) error { return nil })
+	assert.EqualError(t, err, "frequency_penalty must be between -2.0 and 2.0, got -3")
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+	_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	_, _ = client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}, PresencePenalty: gpt3.Float32Ptr(-2), FrequencyPenalty: gpt3.Float32Ptr(0)})
+	assert.Equal(t, 2, rt.RoundTripCallCount())
+
+	body, err := ioutil.ReadAll(rt.RoundTripArgsForCall(0).Body)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "penalty")
+	body, err = ioutil.ReadAll(rt.RoundTripArgsForCall(1).Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"presence_penalty":-2,"frequency_penalty":0`)
+}
+
+func TestInterviewQuestionsEngine(t *testing.T) {
+	ctx := context.Background()
+	jobTitle := "Software Engineer"
+	input := gpt3.InterviewInput{JobTitle: &jobTitle}
+
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(strings.NewReader(
+			`{"choices":[{"text":"1. Why Go?\n2. What is a goroutine?","index":0,"finish_reason":"stop"}]}`)),
+	}, nil)
+
+	settings := gpt3.NewInterviewSettings("user")
+	settings.Engine = gpt3.StringPtr(gpt3.TextDavinci003Engine)
+	resp, err := client.InterviewQuestions(ctx, input, settings, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Why Go?\n\nWhat is a goroutine?", resp.QuestionText())
+	assert.Equal(t, "/v1/engines/text-davinci-003/completions", rt.RoundTripArgsForCall(0).URL.Path)
+
+	rt, httpClient = fakeHttpClient()
+	client = gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(strings.NewReader(`{"choices":[{"index":0,"finish_reason":"stop",` +
+			`"message":{"role":"assistant","content":"- Why Go?\n- What is a goroutine?"}}]}`)),
+	}, nil)
+
+	settings = gpt3.NewInterviewSettings("user")
+	settings.Engine = gpt3.StringPtr(gpt3.GPT3Dot5Turbo)
+	resp, err = client.InterviewQuestions(ctx, input, settings, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Why Go?\n\nWhat is a goroutine?", resp.QuestionText())
+
+	req := rt.RoundTripArgsForCall(0)
+	assert.Equal(t, "/v1/chat/completions", req.URL.Path)
+	var body gpt3.ChatCompletionRequest
+	assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+	assert.Equal(t, gpt3.GPT3Dot5Turbo, body.Model)
+	assert.Equal(t, []gpt3.ChatCompletionRequestMessage{{
+		Role:    gpt3.RoleUser,
+		Content: "Create a list of questions for my interview with a Software Engineer",
+	}}, body.Messages)
+
+	rt, httpClient = fakeHttpClient()
+	client = gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(strings.NewReader(
+			`{"choices":[{"text":"1. Why Go?","index":0,"finish_reason":"stop"}]}`)),
+	}, nil)
+
+	settings = &gpt3.InterviewRequestSettings{User: "user"}
+	resp, err = client.InterviewQuestions(ctx, input, settings, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/v1/engines/text-davinci-001/completions", rt.RoundTripArgsForCall(0).URL.Path)
+	assert.Nil(t, settings.Engine)
+	assert.Equal(t, gpt3.StringPtr(gpt3.InterviewDefaultEngine), resp.Request.Settings.Engine)
+}
+
+func TestInterviewQuestionsStream(t *testing.T) {
+	ctx := context.Background()
+	jobTitle := "Software Engineer"
+	input := gpt3.InterviewInput{JobTitle: &jobTitle}
+
+	chunk := func(text, finishReason string) string {
+		data, _ := json.Marshal(gpt3.CompletionResponse{
+			Choices: []gpt3.CompletionResponseChoice{{Text: text, FinishReason: finishReason}},
+		})
+		return string(data)
+	}
+
+	type testCase struct {
+		name     string
+		cap      int
+		events   []string
+		expected []gpt3.InterviewQuestion
+	}
+
+	testCases := []testCase{
+		{
+			"Questions split across chunks",
+			5,
+			[]string{
+				chunk("\n\n1. What is your gre", ""),
+				chunk("atest strength?\n", ""),
+				chunk("2. Describe a time you", ""),
+				chunk(" led a team.\n3", ""),
+				chunk(". Why Go?", gpt3.FinishReasonStop),
+				"[DONE]",
+			},
+			[]gpt3.InterviewQuestion{
+				{Index: 1, Question: "What is your greatest strength?"},
+				{Index: 2, Question: "Describe a time you led a team."},
+				{Index: 3, Question: "Why Go?"},
+			},
+		},
+		{
+			"Truncated last question",
+			5,
+			[]string{
+				chunk("1. Why Go?\n2. Tell me about", ""),
+				chunk(" a time you", gpt3.FinishReasonLength),
+				"[DONE]",
+			},
+			[]gpt3.InterviewQuestion{{Index: 1, Question: "Why Go?"}},
+		},
+		{
+			"Stops at cap",
+			2,
+			[]string{
+				chunk("1. Why Go?\n2. Why Rust?\n", ""),
+				chunk("3. Why Zig?\n", ""),
+				"[DONE]",
+			},
+			[]gpt3.InterviewQuestion{{Index: 1, Question: "Why Go?"}, {Index: 2, Question: "Why Rust?"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt, httpClient := fakeHttpClient()
+			client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+			rt.RoundTripReturns(fakeStreamResponse(tc.events...), nil)
+
+			var questions []gpt3.InterviewQuestion
+			err := client.InterviewQuestionsStream(ctx, input, gpt3.NewInterviewSettings("user"),
+				gpt3.NewInterviewOptions(tc.cap), func(q gpt3.InterviewQuestion) {
+					questions = append(questions, q)
+				})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, questions)
+		})
+	}
+}
+
+func TestInterviewQuestionsStreamChat(t *testing.T) {
+	ctx := context.Background()
+	jobTitle := "Software Engineer"
+	input := gpt3.InterviewInput{JobTitle: &jobTitle}
+
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+	rt.RoundTripReturns(fakeStreamResponse(
+		`{"choices":[{"delta":{"role":"assistant"}}]}`,
+		`{"choices":[{"delta":{"content":"- Why G"}}]}`,
+		`{"choices":[{"delta":{"content":"o?\n- What is a goroutine?"},"finish_reason":"stop"}]}`,
+		"[DONE]",
+	), nil)
+
+	settings := gpt3.NewInterviewSettings("user")
+	settings.Engine = gpt3.StringPtr(gpt3.GPT3Dot5Turbo)
+	var questions []string
+	err := client.InterviewQuestionsStream(ctx, input, settings, nil, func(q gpt3.InterviewQuestion) {
+		questions = append(questions, q.Question)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Why Go?", "What is a goroutine?"}, questions)
+	assert.Equal(t, "/v1/chat/completions", rt.RoundTripArgsForCall(0).URL.Path)
+
+	err = client.InterviewQuestionsStream(ctx, gpt3.InterviewInput{}, settings, nil, func(gpt3.InterviewQuestion) {})
+	assert.EqualError(t, err, "must specify a job title or description")
+}
+
+func TestSearchFile(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	_, err := client.Search(ctx, gpt3.SearchRequest{Documents: []string{"a"}, File: "file-123", Query: "q"})
+	assert.EqualError(t, err, "only one of documents or file can be searched")
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(strings.NewReader(`{"object":"list","data":[` +
+			`{"document":0,"object":"search_result","score":215.4,"text":"White House","metadata":"source-1"}]}`)),
+	}, nil)
+	resp, err := client.Search(ctx, gpt3.SearchRequest{
+		File:           "file-123",
+		Query:          "the president's house",
+		MaxRerank:      gpt3.IntPtr(10),
+		ReturnMetadata: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []gpt3.SearchData{{
+		Document: 0,
+		Object:   "search_result",
+		Score:    215.4,
+		Text:     "White House",
+		Metadata: "source-1",
+	}}, resp.Data)
+
+	body, err := ioutil.ReadAll(rt.RoundTripArgsForCall(0).Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"file":"file-123","query":"the president's house","max_rerank":10,"return_metadata":true}`,
+		string(body))
+}
+
+func TestRawResponses(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+
+	body := `{"id":"123","choices":[{"text":"output","index":0}],"new_field":{"nested":true}}`
+	rt.RoundTripReturns(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil)
+	raw, resp, err := client.CompletionRaw(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "123", resp.ID)
+	assert.Equal(t, "output", resp.Choices[0].Text)
+	assert.JSONEq(t, body, string(raw))
+
+	body = `{"id":"chatcmpl-123","choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}],"new_field":1}`
+	rt.RoundTripReturns(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil)
+	raw, chatResp, err := client.ChatCompletionRaw(ctx, gpt3.ChatCompletionRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", chatResp.Choices[0].Message.Content)
+
+	var extra struct {
+		NewField int `json:"new_field"`
+	}
+	assert.NoError(t, json.Unmarshal(raw, &extra))
+	assert.Equal(t, 1, extra.NewField)
+}
+
+func TestWithLogger(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+
+	type logEntry struct {
+		req  *http.Request
+		resp *http.Response
+		body []byte
+	}
+	var logged []logEntry
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient),
+		gpt3.WithLogger(func(req *http.Request, resp *http.Response, body []byte) {
+			logged = append(logged, logEntry{req, resp, body})
+		}))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"id":"123","choices":[{"text":"output"}]}`)),
+	}, nil)
+	resp, err := client.Completion(ctx, gpt3.CompletionRequest{Prompt: []string{"hello"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "output", resp.Choices[0].Text)
+	assert.Equal(t, "Bearer test-key", rt.RoundTripArgsForCall(0).Header.Get("Authorization"))
+
+	assert.Len(t, logged, 1)
+	assert.Equal(t, "[REDACTED]", logged[0].req.Header.Get("Authorization"))
+	assert.Equal(t, 200, logged[0].resp.StatusCode)
+	assert.JSONEq(t, `{"id":"123","choices":[{"text":"output"}]}`, string(logged[0].body))
+	reqBody, err := logged[0].req.GetBody()
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(reqBody)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"prompt":["hello"]`)
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 400,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"error":{"message":"bad","type":"invalid_request_error"}}`)),
+	}, nil)
+	_, err = client.Models(ctx)
+	assert.EqualError(t, err, "[400:invalid_request_error] bad")
+	assert.Len(t, logged, 2)
+	assert.Equal(t, 400, logged[1].resp.StatusCode)
+	assert.Contains(t, string(logged[1].body), "bad")
+
+	rt.RoundTripReturns(fakeStreamResponse(`{"id":"123","choices":[{"text":"output"}]}`, "[DONE]"), nil)
+	err = client.CompletionStream(ctx, gpt3.CompletionRequest{Prompt: []string{"test"}}, func(*gpt3.CompletionResponse) error { return nil })
+	assert.NoError(t, err)
+	assert.Len(t, logged, 3)
+	assert.Nil(t, logged[2].body)
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+	_, err = client.Models(ctx)
+	assert.Error(t, err)
+	assert.Len(t, logged, 4)
+	assert.Nil(t, logged[3].resp)
+}
+
+func TestWithObserver(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+
+	type observation struct {
+		endpoint string
+		status   int
+	}
+	var observed []observation
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient),
+		gpt3.WithObserver(func(endpoint string, status int, dur time.Duration) {
+			assert.True(t, dur >= 0)
+			observed = append(observed, observation{endpoint, status})
+		}))
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"id":"chatcmpl-123"}`)),
+	}, nil)
+	_, err := client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{})
+	assert.NoError(t, err)
+
+	rt.RoundTripReturns(&http.Response{
+		StatusCode: 404,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"error":{"message":"not found","type":"invalid_request_error"}}`)),
+	}, nil)
+	_ = client.ListFineTuneEvents(ctx, "ft-123", true, func(*gpt3.FineTuneEvent) {})
+
+	rt.RoundTripReturns(nil, errors.New("request error"))
+	_, _ = client.Models(ctx)
+
+	assert.Equal(t, []observation{
+		{"/chat/completions", 200},
+		{"/fine-tunes/ft-123/events", 404},
+		{"/models", 0},
+	}, observed)
+}
+
+func TestWithRateLimit(t *testing.T) {
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), gpt3.WithRateLimit(20, 1))
+	rt.RoundTripStub = func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{}`))}, nil
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := client.Models(context.Background())
+		assert.NoError(t, err)
+	}
+	// the first request is allowed straight away and each of the others waits 50ms
+	assert.True(t, time.Since(start) >= 190*time.Millisecond, "took %s", time.Since(start))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, _ = client.Models(context.Background())
+	_, err := client.Models(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _ = client.Models(context.Background())
+	_, err = client.Models(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 7, rt.RoundTripCallCount())
+}
+
+func TestUserOmittedWhenEmpty(t *testing.T) {
+	for _, request := range []interface{}{
+		gpt3.CompletionRequest{Prompt: []string{"test"}},
+		gpt3.ChatCompletionRequest{},
+	} {
+		data, err := json.Marshal(request)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), `"user"`)
+	}
+
+	for _, request := range []interface{}{
+		gpt3.CompletionRequest{Prompt: []string{"test"}, User: "user-1"},
+		gpt3.ChatCompletionRequest{User: "user-1"},
+	} {
+		data, err := json.Marshal(request)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"user":"user-1"`)
+	}
+}
+
+func TestStopSequences(t *testing.T) {
+	for _, stop := range []gpt3.StopSequences{nil, {}} {
+		data, err := json.Marshal(gpt3.CompletionRequest{Prompt: []string{"test"}, Stop: stop})
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), `"stop"`)
+	}
+
+	data, err := json.Marshal(gpt3.ChatCompletionRequest{Stop: gpt3.StopString("\n")})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"stop":"\n"`)
+
+	data, err = json.Marshal(gpt3.ChatCompletionRequest{Stop: []string{"\n", "END"}})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"stop":["\n","END"]`)
+
+	for input, expected := range map[string]gpt3.StopSequences{
+		`{"stop":"END"}`:         {"END"},
+		`{"stop":["a","b"]}`:     {"a", "b"},
+		`{"stop":null}`:          nil,
+		`{"prompt":["no stop"]}`: nil,
+	} {
+		var request gpt3.CompletionRequest
+		assert.NoError(t, json.Unmarshal([]byte(input), &request), input)
+		assert.Equal(t, expected, request.Stop, input)
+	}
+
+	var request gpt3.CompletionRequest
+	assert.EqualError(t, json.Unmarshal([]byte(`{"stop":1}`), &request),
+		"stop must be a string or an array of strings: json: cannot unmarshal number into Go value of type []string")
+}
+
+func TestCompletionRequestValidate(t *testing.T) {
+	type testCase struct {
+		name        string
+		request     gpt3.CompletionRequest
+		errorString string
+	}
+
+	prompt := []string{"test"}
+	testCases := []testCase{
+		{"Valid", gpt3.CompletionRequest{Prompt: prompt, MaxTokens: gpt3.IntPtr(16), N: gpt3.IntPtr(1)}, ""},
+		{"No prompt", gpt3.CompletionRequest{}, "prompt must have at least one prompt"},
+		{"MaxTokens", gpt3.CompletionRequest{Prompt: prompt, MaxTokens: gpt3.IntPtr(-1)},
+			"max_tokens must be greater than 0, got -1"},
+		{"Stop", gpt3.CompletionRequest{Prompt: prompt, Stop: []string{"a", "b", "c", "d", "e"}},
+			"stop can have at most 4 sequences, got 5"},
+		{"Temperature", gpt3.CompletionRequest{Prompt: prompt, Temperature: gpt3.Float32Ptr(2.5)},
+			"temperature must be between 0 and 2, got 2.5"},
+		{"TopP", gpt3.CompletionRequest{Prompt: prompt, TopP: gpt3.Float32Ptr(-0.1)},
+			"top_p must be between 0 and 1, got -0.1"},
+		{"N", gpt3.CompletionRequest{Prompt: prompt, N: gpt3.IntPtr(0)}, "n must be at least 1, got 0"},
+		{"BestOf", gpt3.CompletionRequest{Prompt: prompt, N: gpt3.IntPtr(2), BestOf: gpt3.IntPtr(3)}, ""},
+		{"BestOf below N", gpt3.CompletionRequest{Prompt: prompt, N: gpt3.IntPtr(3), BestOf: gpt3.IntPtr(2)},
+			"best_of must be greater than or equal to n, got best_of 2 and n 3"},
+		{"BestOf zero", gpt3.CompletionRequest{Prompt: prompt, BestOf: gpt3.IntPtr(0)}, "best_of must be at least 1, got 0"},
+		{"BestOf expensive", gpt3.CompletionRequest{Prompt: prompt, BestOf: gpt3.IntPtr(20)},
+			"best_of of 20 bills 20 completions for each prompt, set AllowExpensiveBestOf to allow more than 5"},
+		{"BestOf expensive allowed",
+			gpt3.CompletionRequest{Prompt: prompt, BestOf: gpt3.IntPtr(20), AllowExpensiveBestOf: true}, ""},
+		{"PresencePenalty", gpt3.CompletionRequest{Prompt: prompt, PresencePenalty: gpt3.Float32Ptr(3)},
+			"presence_penalty must be between -2.0 and 2.0, got 3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.request.Validate()
+			if tc.errorString == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.errorString)
+			}
+		})
+	}
+
+	rt, httpClient := fakeHttpClient()
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+	_, err := client.Completion(context.Background(), gpt3.CompletionRequest{})
+	assert.EqualError(t, err, "prompt must have at least one prompt")
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+}
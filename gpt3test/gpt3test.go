@@ -0,0 +1,31 @@
+// Package gpt3test provides utilities for testing code that uses the gpt3 package, without a network or an API key.
+package gpt3test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/teamjobot/go-gpt3"
+)
+
+// NewTestClient returns a client whose requests are served in memory by handler. The handler sees the same requests
+// that would be sent to the API, so it can assert on them and write any response, including errors and streams.
+// Further client options, such as gpt3.WithRetry, can be passed in opts.
+func NewTestClient(handler http.Handler, opts ...gpt3.ClientOption) gpt3.Client {
+	httpClient := &http.Client{Transport: handlerTransport{handler}}
+	return gpt3.NewClient("test-key", append([]gpt3.ClientOption{gpt3.WithHTTPClient(httpClient)}, opts...)...)
+}
+
+// handlerTransport is a http.RoundTripper that serves requests with a http.Handler
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
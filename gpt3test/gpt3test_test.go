@@ -0,0 +1,104 @@
+package gpt3test_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+	"github.com/teamjobot/go-gpt3/gpt3test"
+)
+
+func TestNewTestClient(t *testing.T) {
+	client := gpt3test.NewTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var request gpt3.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":"echo %s"}}]}`,
+			request.Messages[0].Content)
+	}))
+
+	resp, err := client.ChatCompletion(context.Background(), gpt3.ChatCompletionRequest{
+		Messages: gpt3.NewMessages().User("hello").Build(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "echo hello", resp.Choices[0].Message.Content)
+}
+
+func TestNewTestClientErrorsAndStreams(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"bad key","type":"invalid_request_error"}}`)
+	})
+	mux.HandleFunc("/v1/engines/davinci/completions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"text\":\"a\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"text\":\"b\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	client := gpt3test.NewTestClient(mux)
+
+	_, err := client.Models(context.Background())
+	assert.True(t, errors.As(err, &gpt3.AuthenticationError{}))
+
+	var text string
+	err = client.CompletionStream(context.Background(), gpt3.CompletionRequest{Prompt: []string{"test"}},
+		func(resp *gpt3.CompletionResponse) error {
+			text += resp.Choices[0].Text
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", text)
+}
+
+func TestStubClient(t *testing.T) {
+	stub := &gpt3test.StubClient{
+		CompletionFunc: func(ctx context.Context, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+			return &gpt3.CompletionResponse{Choices: []gpt3.CompletionResponseChoice{{Text: request.Prompt[0] + "!"}}}, nil
+		},
+	}
+	var client gpt3.Client = stub
+
+	resp, err := client.Completion(context.Background(), gpt3.CompletionRequest{Prompt: []string{"hi"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "hi!", resp.Choices[0].Text)
+
+	embeddings, err := client.Embeddings(context.Background(), gpt3.EmbeddingsRequest{Input: []string{"a", "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, gpt3test.PlaceholderPrefix+"Embeddings", embeddings.Object)
+	assert.Len(t, embeddings.Data, 2)
+
+	responses, errs := client.CompletionBatch(context.Background(), make([]gpt3.CompletionRequest, 2), 1)
+	assert.Len(t, responses, 2)
+	assert.Len(t, errs, 2)
+	for i := range responses {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, gpt3test.PlaceholderPrefix+"CompletionBatch", responses[i].Choices[0].Text)
+	}
+
+	var chunks []string
+	err = client.ChatCompletionStream(context.Background(), gpt3.ChatCompletionRequest{},
+		func(chunk *gpt3.ChatCompletionStreamResponse) {
+			chunks = append(chunks, chunk.Choices[0].Delta.Content)
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{gpt3test.PlaceholderPrefix + "ChatCompletionStream"}, chunks)
+
+	stream, err := client.CompletionStreamReader(context.Background(), gpt3.CompletionRequest{})
+	assert.NoError(t, err)
+	chunk, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, gpt3test.PlaceholderPrefix+"CompletionStreamReader", chunk.Choices[0].Text)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	assert.NoError(t, client.Close())
+}
@@ -0,0 +1,565 @@
+package gpt3test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/teamjobot/go-gpt3"
+)
+
+// PlaceholderPrefix starts the text of the placeholder responses StubClient returns from methods whose func hasn't
+// been set, followed by the name of the method, so code under test can tell them apart from real responses
+const PlaceholderPrefix = "gpt3test placeholder for StubClient."
+
+// StubClient is a gpt3.Client that returns canned results, for testing code that uses a client without any HTTP.
+// Each method calls the func field of the same name, for example Completion calls CompletionFunc. When a func isn't
+// set, the method returns a placeholder response, whose text or id is PlaceholderPrefix followed by the name of the
+// method, and no error. Streaming methods deliver the placeholder as a single chunk, and Close does nothing.
+type StubClient struct {
+	EnginesFunc                       func(context.Context) (*gpt3.EnginesResponse, error)
+	EngineFunc                        func(context.Context, string) (*gpt3.EngineObject, error)
+	ModelsFunc                        func(context.Context) (*gpt3.ModelsResponse, error)
+	PingFunc                          func(context.Context) error
+	ModelFunc                         func(context.Context, string) (*gpt3.ModelObject, error)
+	DeleteModelFunc                   func(context.Context, string) (*gpt3.DeleteModelResponse, error)
+	ChatCompletionFunc                func(context.Context, gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error)
+	ChatCompletionRawFunc             func(context.Context, gpt3.ChatCompletionRequest) (json.RawMessage, *gpt3.ChatCompletionResponse, error)
+	ChatCompletionStreamFunc          func(context.Context, gpt3.ChatCompletionRequest, func(*gpt3.ChatCompletionStreamResponse)) error
+	ChatCompletionStreamWithUsageFunc func(context.Context, gpt3.ChatCompletionRequest, func(*gpt3.ChatCompletionStreamResponse)) (*gpt3.Usage, error)
+	ChatCompletionStreamCollectFunc   func(context.Context, gpt3.ChatCompletionRequest, func(string)) (*gpt3.ChatCompletionResponse, error)
+	CompletionFunc                    func(context.Context, gpt3.CompletionRequest) (*gpt3.CompletionResponse, error)
+	CompletionRawFunc                 func(context.Context, gpt3.CompletionRequest) (json.RawMessage, *gpt3.CompletionResponse, error)
+	CompletionStreamFunc              func(context.Context, gpt3.CompletionRequest, func(*gpt3.CompletionResponse) error) error
+	CompletionStreamTextFunc          func(context.Context, gpt3.CompletionRequest, func(*gpt3.CompletionResponse) error) (string, error)
+	CompletionStreamReaderFunc        func(context.Context, gpt3.CompletionRequest) (*gpt3.CompletionStream, error)
+	CompletionBatchFunc               func(context.Context, []gpt3.CompletionRequest, int) ([]*gpt3.CompletionResponse, []error)
+	CompletionWithEngineFunc          func(context.Context, string, gpt3.CompletionRequest) (*gpt3.CompletionResponse, error)
+	CompletionStreamWithEngineFunc    func(context.Context, string, gpt3.CompletionRequest, func(*gpt3.CompletionResponse) error) error
+	InsertFunc                        func(context.Context, string, string, gpt3.CompletionRequest) (*gpt3.CompletionResponse, error)
+	InsertWithEngineFunc              func(context.Context, string, string, string, gpt3.CompletionRequest) (*gpt3.CompletionResponse, error)
+	EditsFunc                         func(context.Context, gpt3.EditsRequest) (*gpt3.EditsResponse, error)
+	InterviewQuestionsFunc            func(context.Context, gpt3.InterviewInput, *gpt3.InterviewRequestSettings, *gpt3.InterviewOptions) (*gpt3.InterviewResponse, error)
+	InterviewQuestionsStreamFunc      func(context.Context, gpt3.InterviewInput, *gpt3.InterviewRequestSettings, *gpt3.InterviewOptions, func(gpt3.InterviewQuestion)) error
+	SearchFunc                        func(context.Context, gpt3.SearchRequest) (*gpt3.SearchResponse, error)
+	SearchWithEngineFunc              func(context.Context, string, gpt3.SearchRequest) (*gpt3.SearchResponse, error)
+	EmbeddingsFunc                    func(context.Context, gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error)
+	EmbeddingsBatchFunc               func(context.Context, string, []string) (*gpt3.EmbeddingsResponse, error)
+	ModerationsFunc                   func(context.Context, gpt3.ModerationRequest) (*gpt3.ModerationResponse, error)
+	ContentFilterFunc                 func(context.Context, string) (gpt3.FilterLabel, error)
+	CreateImageFunc                   func(context.Context, gpt3.ImageRequest) (*gpt3.ImageResponse, error)
+	CreateImageEditFunc               func(context.Context, gpt3.ImageEditRequest) (*gpt3.ImageResponse, error)
+	CreateImageVariationFunc          func(context.Context, gpt3.ImageVariationRequest) (*gpt3.ImageResponse, error)
+	CreateTranscriptionFunc           func(context.Context, gpt3.AudioRequest) (*gpt3.AudioResponse, error)
+	CreateTranslationFunc             func(context.Context, gpt3.AudioRequest) (*gpt3.AudioResponse, error)
+	UploadFileFunc                    func(context.Context, gpt3.FileRequest) (*gpt3.FileObject, error)
+	ListFilesFunc                     func(context.Context) (*gpt3.FilesResponse, error)
+	RetrieveFileFunc                  func(context.Context, string) (*gpt3.FileObject, error)
+	DeleteFileFunc                    func(context.Context, string) (*gpt3.DeleteFileResponse, error)
+	CreateFineTuneFunc                func(context.Context, gpt3.FineTuneRequest) (*gpt3.FineTune, error)
+	ListFineTunesFunc                 func(context.Context) (*gpt3.FineTunesResponse, error)
+	RetrieveFineTuneFunc              func(context.Context, string) (*gpt3.FineTune, error)
+	CancelFineTuneFunc                func(context.Context, string) (*gpt3.FineTune, error)
+	ListFineTuneEventsFunc            func(context.Context, string, bool, func(*gpt3.FineTuneEvent)) error
+	CloseFunc                         func() error
+}
+
+var _ gpt3.Client = (*StubClient)(nil)
+
+// placeholder returns the text of the placeholder response of method
+func placeholder(method string) string {
+	return PlaceholderPrefix + method
+}
+
+func placeholderCompletion(method string) *gpt3.CompletionResponse {
+	return &gpt3.CompletionResponse{
+		ID:      placeholder(method),
+		Object:  "text_completion",
+		Choices: []gpt3.CompletionResponseChoice{{Text: placeholder(method), FinishReason: gpt3.FinishReasonStop}},
+	}
+}
+
+func placeholderChatCompletion(method string, request gpt3.ChatCompletionRequest) *gpt3.ChatCompletionResponse {
+	return &gpt3.ChatCompletionResponse{
+		ID:     placeholder(method),
+		Object: "chat.completion",
+		Model:  request.Model,
+		Choices: []gpt3.ChatCompletionResponseChoice{{
+			FinishReason: gpt3.FinishReasonStop,
+			Message:      gpt3.ChatCompletionResponseMessage{Role: gpt3.RoleAssistant, Content: placeholder(method)},
+		}},
+	}
+}
+
+func placeholderChatChunk(method string, request gpt3.ChatCompletionRequest) *gpt3.ChatCompletionStreamResponse {
+	return &gpt3.ChatCompletionStreamResponse{
+		ID:     placeholder(method),
+		Object: "chat.completion.chunk",
+		Model:  request.Model,
+		Choices: []gpt3.ChatCompletionStreamResponseChoice{{
+			FinishReason: gpt3.FinishReasonStop,
+			Delta:        gpt3.ChatCompletionResponseMessage{Role: gpt3.RoleAssistant, Content: placeholder(method)},
+		}},
+	}
+}
+
+// placeholderStream returns a stream of a single placeholder chunk, read from a test client like a real stream. The
+// test client is sent a request of its own, so the placeholder doesn't depend on the request being valid.
+func placeholderStream(ctx context.Context) (*gpt3.CompletionStream, error) {
+	chunk := rawPlaceholder(placeholderCompletion("CompletionStreamReader"))
+	client := NewTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", chunk)
+	}))
+	request := gpt3.CompletionRequest{Prompt: []string{placeholder("CompletionStreamReader")}}
+	return client.CompletionStreamReader(ctx, request)
+}
+
+func placeholderSearch(method string) *gpt3.SearchResponse {
+	return &gpt3.SearchResponse{
+		Object: "list",
+		Data:   []gpt3.SearchData{{Object: "search_result", Text: placeholder(method)}},
+	}
+}
+
+func placeholderEmbeddings(method string, inputs int) *gpt3.EmbeddingsResponse {
+	resp := &gpt3.EmbeddingsResponse{Object: placeholder(method)}
+	for i := 0; i < inputs; i++ {
+		resp.Data = append(resp.Data, gpt3.Embedding{Object: "embedding", Embedding: []float32{0}, Index: i})
+	}
+	return resp
+}
+
+func placeholderImage(method string) *gpt3.ImageResponse {
+	return &gpt3.ImageResponse{Data: []gpt3.ImageData{{URL: placeholder(method)}}}
+}
+
+func placeholderFineTune(method string, status string) *gpt3.FineTune {
+	return &gpt3.FineTune{ID: placeholder(method), Object: "fine-tune", Status: status}
+}
+
+// rawPlaceholder returns the JSON encoding of a placeholder response, which can't fail for these types
+func rawPlaceholder(v interface{}) json.RawMessage {
+	raw, _ := json.Marshal(v)
+	return raw
+}
+
+func (s *StubClient) Engines(ctx context.Context) (*gpt3.EnginesResponse, error) {
+	if s.EnginesFunc == nil {
+		return &gpt3.EnginesResponse{
+			Object: "list",
+			Data:   []gpt3.EngineObject{{ID: placeholder("Engines"), Object: "engine", Ready: true}},
+		}, nil
+	}
+	return s.EnginesFunc(ctx)
+}
+
+func (s *StubClient) Engine(ctx context.Context, engine string) (*gpt3.EngineObject, error) {
+	if s.EngineFunc == nil {
+		return &gpt3.EngineObject{ID: placeholder("Engine"), Object: "engine", Ready: true}, nil
+	}
+	return s.EngineFunc(ctx, engine)
+}
+
+func (s *StubClient) Models(ctx context.Context) (*gpt3.ModelsResponse, error) {
+	if s.ModelsFunc == nil {
+		return &gpt3.ModelsResponse{
+			Object: "list",
+			Data:   []gpt3.ModelObject{{ID: placeholder("Models"), Object: "model"}},
+		}, nil
+	}
+	return s.ModelsFunc(ctx)
+}
+
+func (s *StubClient) Ping(ctx context.Context) error {
+	if s.PingFunc == nil {
+		return nil
+	}
+	return s.PingFunc(ctx)
+}
+
+func (s *StubClient) Model(ctx context.Context, id string) (*gpt3.ModelObject, error) {
+	if s.ModelFunc == nil {
+		return &gpt3.ModelObject{ID: placeholder("Model"), Object: "model"}, nil
+	}
+	return s.ModelFunc(ctx, id)
+}
+
+func (s *StubClient) DeleteModel(ctx context.Context, id string) (*gpt3.DeleteModelResponse, error) {
+	if s.DeleteModelFunc == nil {
+		return &gpt3.DeleteModelResponse{ID: placeholder("DeleteModel"), Object: "model", Deleted: true}, nil
+	}
+	return s.DeleteModelFunc(ctx, id)
+}
+
+func (s *StubClient) ChatCompletion(
+	ctx context.Context,
+	request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	if s.ChatCompletionFunc == nil {
+		return placeholderChatCompletion("ChatCompletion", request), nil
+	}
+	return s.ChatCompletionFunc(ctx, request)
+}
+
+func (s *StubClient) ChatCompletionRaw(
+	ctx context.Context,
+	request gpt3.ChatCompletionRequest) (json.RawMessage, *gpt3.ChatCompletionResponse, error) {
+	if s.ChatCompletionRawFunc == nil {
+		resp := placeholderChatCompletion("ChatCompletionRaw", request)
+		return rawPlaceholder(resp), resp, nil
+	}
+	return s.ChatCompletionRawFunc(ctx, request)
+}
+
+func (s *StubClient) ChatCompletionStream(
+	ctx context.Context,
+	request gpt3.ChatCompletionRequest,
+	onData func(*gpt3.ChatCompletionStreamResponse)) error {
+	if s.ChatCompletionStreamFunc == nil {
+		onData(placeholderChatChunk("ChatCompletionStream", request))
+		return nil
+	}
+	return s.ChatCompletionStreamFunc(ctx, request, onData)
+}
+
+func (s *StubClient) ChatCompletionStreamWithUsage(
+	ctx context.Context,
+	request gpt3.ChatCompletionRequest,
+	onData func(*gpt3.ChatCompletionStreamResponse)) (*gpt3.Usage, error) {
+	if s.ChatCompletionStreamWithUsageFunc == nil {
+		onData(placeholderChatChunk("ChatCompletionStreamWithUsage", request))
+		return nil, nil
+	}
+	return s.ChatCompletionStreamWithUsageFunc(ctx, request, onData)
+}
+
+func (s *StubClient) ChatCompletionStreamCollect(
+	ctx context.Context,
+	request gpt3.ChatCompletionRequest,
+	onDelta func(string)) (*gpt3.ChatCompletionResponse, error) {
+	if s.ChatCompletionStreamCollectFunc == nil {
+		if onDelta != nil {
+			onDelta(placeholder("ChatCompletionStreamCollect"))
+		}
+		return placeholderChatCompletion("ChatCompletionStreamCollect", request), nil
+	}
+	return s.ChatCompletionStreamCollectFunc(ctx, request, onDelta)
+}
+
+func (s *StubClient) Completion(ctx context.Context, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	if s.CompletionFunc == nil {
+		return placeholderCompletion("Completion"), nil
+	}
+	return s.CompletionFunc(ctx, request)
+}
+
+func (s *StubClient) CompletionRaw(
+	ctx context.Context,
+	request gpt3.CompletionRequest) (json.RawMessage, *gpt3.CompletionResponse, error) {
+	if s.CompletionRawFunc == nil {
+		resp := placeholderCompletion("CompletionRaw")
+		return rawPlaceholder(resp), resp, nil
+	}
+	return s.CompletionRawFunc(ctx, request)
+}
+
+func (s *StubClient) CompletionStream(
+	ctx context.Context,
+	request gpt3.CompletionRequest,
+	onData func(*gpt3.CompletionResponse) error) error {
+	if s.CompletionStreamFunc == nil {
+		return onData(placeholderCompletion("CompletionStream"))
+	}
+	return s.CompletionStreamFunc(ctx, request, onData)
+}
+
+func (s *StubClient) CompletionStreamText(
+	ctx context.Context,
+	request gpt3.CompletionRequest,
+	onData func(*gpt3.CompletionResponse) error) (string, error) {
+	if s.CompletionStreamTextFunc == nil {
+		if onData != nil {
+			if err := onData(placeholderCompletion("CompletionStreamText")); err != nil {
+				return "", err
+			}
+		}
+		return placeholder("CompletionStreamText"), nil
+	}
+	return s.CompletionStreamTextFunc(ctx, request, onData)
+}
+
+func (s *StubClient) CompletionStreamReader(
+	ctx context.Context,
+	request gpt3.CompletionRequest) (*gpt3.CompletionStream, error) {
+	if s.CompletionStreamReaderFunc == nil {
+		return placeholderStream(ctx)
+	}
+	return s.CompletionStreamReaderFunc(ctx, request)
+}
+
+func (s *StubClient) CompletionBatch(
+	ctx context.Context,
+	requests []gpt3.CompletionRequest,
+	concurrency int) ([]*gpt3.CompletionResponse, []error) {
+	if s.CompletionBatchFunc == nil {
+		responses := make([]*gpt3.CompletionResponse, len(requests))
+		for i := range responses {
+			responses[i] = placeholderCompletion("CompletionBatch")
+		}
+		return responses, make([]error, len(requests))
+	}
+	return s.CompletionBatchFunc(ctx, requests, concurrency)
+}
+
+func (s *StubClient) CompletionWithEngine(
+	ctx context.Context,
+	engine string,
+	request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	if s.CompletionWithEngineFunc == nil {
+		return placeholderCompletion("CompletionWithEngine"), nil
+	}
+	return s.CompletionWithEngineFunc(ctx, engine, request)
+}
+
+func (s *StubClient) CompletionStreamWithEngine(
+	ctx context.Context,
+	engine string,
+	request gpt3.CompletionRequest,
+	onData func(*gpt3.CompletionResponse) error) error {
+	if s.CompletionStreamWithEngineFunc == nil {
+		return onData(placeholderCompletion("CompletionStreamWithEngine"))
+	}
+	return s.CompletionStreamWithEngineFunc(ctx, engine, request, onData)
+}
+
+func (s *StubClient) Insert(
+	ctx context.Context,
+	prefix, suffix string,
+	request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	if s.InsertFunc == nil {
+		return placeholderCompletion("Insert"), nil
+	}
+	return s.InsertFunc(ctx, prefix, suffix, request)
+}
+
+func (s *StubClient) InsertWithEngine(
+	ctx context.Context,
+	engine, prefix, suffix string,
+	request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	if s.InsertWithEngineFunc == nil {
+		return placeholderCompletion("InsertWithEngine"), nil
+	}
+	return s.InsertWithEngineFunc(ctx, engine, prefix, suffix, request)
+}
+
+func (s *StubClient) Edits(ctx context.Context, request gpt3.EditsRequest) (*gpt3.EditsResponse, error) {
+	if s.EditsFunc == nil {
+		return &gpt3.EditsResponse{
+			Object:  "edit",
+			Choices: []gpt3.EditsResponseChoice{{Text: placeholder("Edits")}},
+		}, nil
+	}
+	return s.EditsFunc(ctx, request)
+}
+
+func (s *StubClient) InterviewQuestions(
+	ctx context.Context,
+	input gpt3.InterviewInput,
+	settings *gpt3.InterviewRequestSettings,
+	options *gpt3.InterviewOptions) (*gpt3.InterviewResponse, error) {
+	if s.InterviewQuestionsFunc == nil {
+		return &gpt3.InterviewResponse{
+			Options:   options,
+			Questions: []gpt3.InterviewQuestion{{Index: 1, Question: placeholder("InterviewQuestions")}},
+		}, nil
+	}
+	return s.InterviewQuestionsFunc(ctx, input, settings, options)
+}
+
+func (s *StubClient) InterviewQuestionsStream(
+	ctx context.Context,
+	input gpt3.InterviewInput,
+	settings *gpt3.InterviewRequestSettings,
+	options *gpt3.InterviewOptions,
+	onQuestion func(gpt3.InterviewQuestion)) error {
+	if s.InterviewQuestionsStreamFunc == nil {
+		onQuestion(gpt3.InterviewQuestion{Index: 1, Question: placeholder("InterviewQuestionsStream")})
+		return nil
+	}
+	return s.InterviewQuestionsStreamFunc(ctx, input, settings, options, onQuestion)
+}
+
+func (s *StubClient) Search(ctx context.Context, request gpt3.SearchRequest) (*gpt3.SearchResponse, error) {
+	if s.SearchFunc == nil {
+		return placeholderSearch("Search"), nil
+	}
+	return s.SearchFunc(ctx, request)
+}
+
+func (s *StubClient) SearchWithEngine(
+	ctx context.Context,
+	engine string,
+	request gpt3.SearchRequest) (*gpt3.SearchResponse, error) {
+	if s.SearchWithEngineFunc == nil {
+		return placeholderSearch("SearchWithEngine"), nil
+	}
+	return s.SearchWithEngineFunc(ctx, engine, request)
+}
+
+func (s *StubClient) Embeddings(ctx context.Context, request gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error) {
+	if s.EmbeddingsFunc == nil {
+		return placeholderEmbeddings("Embeddings", len(request.Input)), nil
+	}
+	return s.EmbeddingsFunc(ctx, request)
+}
+
+func (s *StubClient) EmbeddingsBatch(ctx context.Context, model string, inputs []string) (*gpt3.EmbeddingsResponse, error) {
+	if s.EmbeddingsBatchFunc == nil {
+		return placeholderEmbeddings("EmbeddingsBatch", len(inputs)), nil
+	}
+	return s.EmbeddingsBatchFunc(ctx, model, inputs)
+}
+
+func (s *StubClient) Moderations(
+	ctx context.Context,
+	request gpt3.ModerationRequest) (*gpt3.ModerationResponse, error) {
+	if s.ModerationsFunc == nil {
+		resp := &gpt3.ModerationResponse{ID: placeholder("Moderations"), Model: request.Model}
+		for range request.Input {
+			resp.Results = append(resp.Results, gpt3.ModerationResult{})
+		}
+		return resp, nil
+	}
+	return s.ModerationsFunc(ctx, request)
+}
+
+func (s *StubClient) ContentFilter(ctx context.Context, text string) (gpt3.FilterLabel, error) {
+	if s.ContentFilterFunc == nil {
+		return gpt3.FilterLabelSafe, nil
+	}
+	return s.ContentFilterFunc(ctx, text)
+}
+
+func (s *StubClient) CreateImage(ctx context.Context, request gpt3.ImageRequest) (*gpt3.ImageResponse, error) {
+	if s.CreateImageFunc == nil {
+		return placeholderImage("CreateImage"), nil
+	}
+	return s.CreateImageFunc(ctx, request)
+}
+
+func (s *StubClient) CreateImageEdit(ctx context.Context, request gpt3.ImageEditRequest) (*gpt3.ImageResponse, error) {
+	if s.CreateImageEditFunc == nil {
+		return placeholderImage("CreateImageEdit"), nil
+	}
+	return s.CreateImageEditFunc(ctx, request)
+}
+
+func (s *StubClient) CreateImageVariation(
+	ctx context.Context,
+	request gpt3.ImageVariationRequest) (*gpt3.ImageResponse, error) {
+	if s.CreateImageVariationFunc == nil {
+		return placeholderImage("CreateImageVariation"), nil
+	}
+	return s.CreateImageVariationFunc(ctx, request)
+}
+
+func (s *StubClient) CreateTranscription(ctx context.Context, request gpt3.AudioRequest) (*gpt3.AudioResponse, error) {
+	if s.CreateTranscriptionFunc == nil {
+		return &gpt3.AudioResponse{Text: placeholder("CreateTranscription")}, nil
+	}
+	return s.CreateTranscriptionFunc(ctx, request)
+}
+
+func (s *StubClient) CreateTranslation(ctx context.Context, request gpt3.AudioRequest) (*gpt3.AudioResponse, error) {
+	if s.CreateTranslationFunc == nil {
+		return &gpt3.AudioResponse{Text: placeholder("CreateTranslation")}, nil
+	}
+	return s.CreateTranslationFunc(ctx, request)
+}
+
+func (s *StubClient) UploadFile(ctx context.Context, request gpt3.FileRequest) (*gpt3.FileObject, error) {
+	if s.UploadFileFunc == nil {
+		return &gpt3.FileObject{
+			ID:       placeholder("UploadFile"),
+			Object:   "file",
+			Filename: request.FileName,
+			Purpose:  request.Purpose,
+		}, nil
+	}
+	return s.UploadFileFunc(ctx, request)
+}
+
+func (s *StubClient) ListFiles(ctx context.Context) (*gpt3.FilesResponse, error) {
+	if s.ListFilesFunc == nil {
+		return &gpt3.FilesResponse{
+			Object: "list",
+			Data:   []gpt3.FileObject{{ID: placeholder("ListFiles"), Object: "file"}},
+		}, nil
+	}
+	return s.ListFilesFunc(ctx)
+}
+
+func (s *StubClient) RetrieveFile(ctx context.Context, id string) (*gpt3.FileObject, error) {
+	if s.RetrieveFileFunc == nil {
+		return &gpt3.FileObject{ID: placeholder("RetrieveFile"), Object: "file"}, nil
+	}
+	return s.RetrieveFileFunc(ctx, id)
+}
+
+func (s *StubClient) DeleteFile(ctx context.Context, id string) (*gpt3.DeleteFileResponse, error) {
+	if s.DeleteFileFunc == nil {
+		return &gpt3.DeleteFileResponse{ID: placeholder("DeleteFile"), Object: "file", Deleted: true}, nil
+	}
+	return s.DeleteFileFunc(ctx, id)
+}
+
+func (s *StubClient) CreateFineTune(ctx context.Context, request gpt3.FineTuneRequest) (*gpt3.FineTune, error) {
+	if s.CreateFineTuneFunc == nil {
+		return placeholderFineTune("CreateFineTune", gpt3.FineTuneStatusPending), nil
+	}
+	return s.CreateFineTuneFunc(ctx, request)
+}
+
+func (s *StubClient) ListFineTunes(ctx context.Context) (*gpt3.FineTunesResponse, error) {
+	if s.ListFineTunesFunc == nil {
+		return &gpt3.FineTunesResponse{
+			Object: "list",
+			Data:   []gpt3.FineTune{*placeholderFineTune("ListFineTunes", gpt3.FineTuneStatusSucceeded)},
+		}, nil
+	}
+	return s.ListFineTunesFunc(ctx)
+}
+
+func (s *StubClient) RetrieveFineTune(ctx context.Context, id string) (*gpt3.FineTune, error) {
+	if s.RetrieveFineTuneFunc == nil {
+		return placeholderFineTune("RetrieveFineTune", gpt3.FineTuneStatusSucceeded), nil
+	}
+	return s.RetrieveFineTuneFunc(ctx, id)
+}
+
+func (s *StubClient) CancelFineTune(ctx context.Context, id string) (*gpt3.FineTune, error) {
+	if s.CancelFineTuneFunc == nil {
+		return placeholderFineTune("CancelFineTune", gpt3.FineTuneStatusCancelled), nil
+	}
+	return s.CancelFineTuneFunc(ctx, id)
+}
+
+func (s *StubClient) ListFineTuneEvents(
+	ctx context.Context,
+	id string,
+	stream bool,
+	onEvent func(*gpt3.FineTuneEvent)) error {
+	if s.ListFineTuneEventsFunc == nil {
+		onEvent(&gpt3.FineTuneEvent{Object: "fine-tune-event", Level: "info", Message: placeholder("ListFineTuneEvents")})
+		return nil
+	}
+	return s.ListFineTuneEventsFunc(ctx, id, stream, onEvent)
+}
+
+func (s *StubClient) Close() error {
+	if s.CloseFunc == nil {
+		return nil
+	}
+	return s.CloseFunc()
+}
@@ -0,0 +1,77 @@
+package gpt3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// jsonOnlyInstruction is appended to the prompts of a CompleteJSON retry
+const jsonOnlyInstruction = "\nReturn valid JSON only."
+
+// JSONError is returned by CompleteJSON when the text of the completion couldn't be parsed. It can be matched with
+// errors.As, as can the json error it wraps.
+type JSONError struct {
+	// Text is the completion text that failed to parse
+	Text string
+	Err  error
+}
+
+func (e JSONError) Error() string {
+	return fmt.Sprintf("invalid json in completion: %v: %q", e.Err, e.Text)
+}
+
+func (e JSONError) Unwrap() error {
+	return e.Err
+}
+
+// CompleteJSON sends request with client and parses the text of the first choice as JSON into a T. Whitespace and a
+// markdown code fence around the JSON are ignored. When the text isn't valid JSON the request is sent again up to
+// retries times, with an instruction to return valid JSON only appended to each prompt. The error of the last attempt
+// is a JSONError holding the text that failed to parse.
+func CompleteJSON[T any](ctx context.Context, client Client, request CompletionRequest, retries int) (T, error) {
+	var output T
+	for attempt := 0; ; attempt++ {
+		rsp, err := client.Completion(ctx, request)
+		if err != nil {
+			return output, err
+		}
+		if len(rsp.Choices) == 0 {
+			return output, errors.New("completion returned no choices")
+		}
+
+		text := rsp.Choices[0].Text
+		err = json.Unmarshal([]byte(trimJSON(text)), &output)
+		if err == nil {
+			return output, nil
+		}
+		if attempt >= retries {
+			return output, JSONError{Text: text, Err: err}
+		}
+
+		if attempt == 0 {
+			prompts := make([]string, len(request.Prompt))
+			for i, prompt := range request.Prompt {
+				prompts[i] = prompt + jsonOnlyInstruction
+			}
+			request.Prompt = prompts
+		}
+		output = *new(T)
+	}
+}
+
+// trimJSON removes whitespace and a markdown code fence around text
+func trimJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "```"), "```")
+	// drop the language of the fence, such as ```json
+	if i := strings.IndexByte(text, '\n'); i >= 0 && !strings.ContainsAny(text[:i], "{[\"") {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(text)
+}
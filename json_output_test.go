@@ -0,0 +1,80 @@
+package gpt3_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+	"github.com/teamjobot/go-gpt3/gpt3test"
+)
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// replyWithTexts returns a client completing with the given texts in order and the prompts it was sent
+func replyWithTexts(texts ...string) (*gpt3test.StubClient, *[][]string) {
+	var prompts [][]string
+	return &gpt3test.StubClient{
+		CompletionFunc: func(ctx context.Context, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+			prompts = append(prompts, request.Prompt)
+			return &gpt3.CompletionResponse{
+				Choices: []gpt3.CompletionResponseChoice{{Text: texts[len(prompts)-1]}},
+			}, nil
+		},
+	}, &prompts
+}
+
+func TestCompleteJSON(t *testing.T) {
+	ctx := context.Background()
+	request := gpt3.CompletionRequest{Prompt: []string{"Describe Ada as JSON:"}}
+
+	t.Run("parses the first choice", func(t *testing.T) {
+		client, prompts := replyWithTexts("```json\n{\"name\": \"Ada\", \"age\": 36}\n```\n")
+
+		output, err := gpt3.CompleteJSON[person](ctx, client, request, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, person{Name: "Ada", Age: 36}, output)
+		assert.Len(t, *prompts, 1)
+	})
+
+	t.Run("retries asking for valid json", func(t *testing.T) {
+		client, prompts := replyWithTexts(`{"name": "Ada",`, `{"name": "Ada", "age": 36}`)
+
+		output, err := gpt3.CompleteJSON[person](ctx, client, request, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, person{Name: "Ada", Age: 36}, output)
+		assert.Equal(t, [][]string{
+			{"Describe Ada as JSON:"},
+			{"Describe Ada as JSON:\nReturn valid JSON only."},
+		}, *prompts)
+		assert.Equal(t, []string{"Describe Ada as JSON:"}, request.Prompt)
+	})
+
+	t.Run("returns the text that failed to parse", func(t *testing.T) {
+		client, prompts := replyWithTexts("Ada is 36", "Sure! Ada is 36")
+
+		_, err := gpt3.CompleteJSON[person](ctx, client, request, 1)
+		var jsonErr gpt3.JSONError
+		assert.True(t, errors.As(err, &jsonErr))
+		assert.Equal(t, "Sure! Ada is 36", jsonErr.Text)
+		var syntaxErr *json.SyntaxError
+		assert.True(t, errors.As(err, &syntaxErr))
+		assert.Len(t, *prompts, 2)
+	})
+
+	t.Run("returns request errors", func(t *testing.T) {
+		requestErr := errors.New("request error")
+		client := &gpt3test.StubClient{
+			CompletionFunc: func(context.Context, gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+				return nil, requestErr
+			},
+		}
+		_, err := gpt3.CompleteJSON[person](ctx, client, request, 1)
+		assert.True(t, errors.Is(err, requestErr))
+	})
+}
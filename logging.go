@@ -0,0 +1,41 @@
+package gpt3
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// redacted replaces the value of headers holding credentials in logged requests
+const redacted = "[REDACTED]"
+
+// logExchange passes a request and its response to the client's logger. resp is nil when the request failed. The
+// response body is buffered and restored so it can still be read, except for successful streams where body is nil
+// so the stream isn't held up.
+func (c *client) logExchange(req *http.Request, resp *http.Response, stream bool) error {
+	if c.logger == nil {
+		return nil
+	}
+
+	logged := req.Clone(req.Context())
+	for _, header := range []string{"Authorization", "api-key"} {
+		if logged.Header.Get(header) != "" {
+			logged.Header.Set(header, redacted)
+		}
+	}
+
+	if resp == nil || (stream && resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		c.logger(logged, resp, nil)
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read from body: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.logger(logged, resp, body)
+	return nil
+}
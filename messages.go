@@ -0,0 +1,53 @@
+package gpt3
+
+// ChatMessages builds the list of messages for a ChatCompletionRequest, for example:
+//
+//	messages := NewMessages().
+//		System("You are a helpful assistant").
+//		User("Hello!").
+//		Build()
+type ChatMessages struct {
+	messages []ChatCompletionRequestMessage
+}
+
+// NewMessages returns an empty ChatMessages builder
+func NewMessages() *ChatMessages {
+	return &ChatMessages{}
+}
+
+// System adds a system message
+func (m *ChatMessages) System(content string) *ChatMessages {
+	return m.add(ChatCompletionRequestMessage{Role: RoleSystem, Content: content})
+}
+
+// User adds a user message
+func (m *ChatMessages) User(content string) *ChatMessages {
+	return m.add(ChatCompletionRequestMessage{Role: RoleUser, Content: content})
+}
+
+// Assistant adds an assistant message, such as a previous reply of the model
+func (m *ChatMessages) Assistant(content string) *ChatMessages {
+	return m.add(ChatCompletionRequestMessage{Role: RoleAssistant, Content: content})
+}
+
+// Tool adds the result of the tool call with the given ID
+func (m *ChatMessages) Tool(toolCallID, content string) *ChatMessages {
+	return m.add(ChatCompletionRequestMessage{Role: RoleTool, Content: content, ToolCallID: toolCallID})
+}
+
+// Message adds a message as is
+func (m *ChatMessages) Message(message ChatCompletionRequestMessage) *ChatMessages {
+	return m.add(message)
+}
+
+// Build returns the messages that were added, in order
+func (m *ChatMessages) Build() []ChatCompletionRequestMessage {
+	messages := make([]ChatCompletionRequestMessage, len(m.messages))
+	copy(messages, m.messages)
+	return messages
+}
+
+func (m *ChatMessages) add(message ChatCompletionRequestMessage) *ChatMessages {
+	m.messages = append(m.messages, message)
+	return m
+}
@@ -0,0 +1,35 @@
+package gpt3_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestChatMessages(t *testing.T) {
+	builder := gpt3.NewMessages().
+		System("be brief").
+		User("what's the weather?").
+		Message(gpt3.ChatCompletionRequestMessage{
+			Role:      gpt3.RoleAssistant,
+			ToolCalls: []gpt3.ToolCall{{ID: "call_1", Type: gpt3.ToolTypeFunction}},
+		}).
+		Tool("call_1", "sunny").
+		Assistant("It's sunny")
+
+	messages := builder.Build()
+	assert.Equal(t, []gpt3.ChatCompletionRequestMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "what's the weather?"},
+		{Role: "assistant", ToolCalls: []gpt3.ToolCall{{ID: "call_1", Type: "function"}}},
+		{Role: "tool", Content: "sunny", ToolCallID: "call_1"},
+		{Role: "assistant", Content: "It's sunny"},
+	}, messages)
+
+	// adding to the builder doesn't change messages that were already built
+	builder.User("thanks")
+	assert.Len(t, messages, 5)
+	assert.Len(t, builder.Build(), 6)
+	assert.Empty(t, gpt3.NewMessages().Build())
+}
@@ -0,0 +1,96 @@
+package gpt3
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Model is the name of an engine or model that Valid checks against the models of this package. The client's methods
+// and requests take model names as strings and the model constants are untyped, so that code written before Model
+// was added still compiles. Instead, the client checks the model of completion, chat completion and embeddings
+// requests with Valid before sending them, so a typo fails with ErrUnknownModel rather than reaching the API. See
+// WithModelValidation for when it does.
+type Model string
+
+// knownModels are the models that Valid accepts besides those with a known context window
+var knownModels = map[Model]bool{
+	ContentFilterEngine:       true,
+	TextSimilarityAda001:      true,
+	TextSimilarityBabbage001:  true,
+	TextSimilarityCurie001:    true,
+	TextSimilarityDavinci001:  true,
+	TextSearchAdaDoc001:       true,
+	TextSearchAdaQuery001:     true,
+	TextSearchBabbageDoc001:   true,
+	TextSearchBabbageQuery001: true,
+	TextSearchCurieDoc001:     true,
+	TextSearchCurieQuery001:   true,
+	TextSearchDavinciDoc001:   true,
+	TextSearchDavinciQuery001: true,
+	CodeSearchAdaCode001:      true,
+	CodeSearchAdaText001:      true,
+	CodeSearchBabbageCode001:  true,
+	CodeSearchBabbageText001:  true,
+	TextModerationLatest:      true,
+	TextModerationStable:      true,
+	Whisper1:                  true,
+}
+
+// snapshotRe matches the name of a dated snapshot of a model, such as "gpt-4-0613" or "gpt-4-turbo-2024-04-09"
+var snapshotRe = regexp.MustCompile(`^(.+)-(\d{4}|\d{4}-\d{2}-\d{2})$`)
+
+// snapshotBase returns the name of the model that model is a dated snapshot of, and whether it is one
+func snapshotBase(model string) (string, bool) {
+	match := snapshotRe.FindStringSubmatch(model)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// Valid returns true if m is a known model or a dated snapshot of one, such as "gpt-4-0613", or the id of a fine-tuned
+// model, which start with "ft:" or, for models fine-tuned with the legacy fine-tunes API, look like
+// "curie:ft-org-2023-01-01-00-00-00". It catches typos in model names before a request is sent, but an Azure
+// deployment name or a model released after this package isn't valid.
+func (m Model) Valid() bool {
+	if m.known() {
+		return true
+	}
+	if base, ok := snapshotBase(string(m)); ok && Model(base).known() {
+		return true
+	}
+	return strings.HasPrefix(string(m), "ft:") || strings.Contains(string(m), ":ft-")
+}
+
+// known returns true if m is one of the models of this package
+func (m Model) known() bool {
+	if knownModels[m] {
+		return true
+	}
+	_, ok := contextWindows[string(m)]
+	return ok
+}
+
+// checkModel returns an error wrapping ErrUnknownModel when the client validates models and model isn't Valid. An
+// empty model is left for the API to reject.
+func (c *client) checkModel(model string) error {
+	if model == "" || !c.validatesModels() || Model(model).Valid() {
+		return nil
+	}
+	return fmt.Errorf("%w %q, use WithModelValidation(false) to send it anyway", ErrUnknownModel, model)
+}
+
+// validatesModels returns whether the client checks the models of requests, which is set by WithModelValidation and
+// otherwise only done for the OpenAI API, since Azure deployments and other servers have models of their own
+func (c *client) validatesModels() bool {
+	if c.modelValidation != nil {
+		return *c.modelValidation
+	}
+	return c.azure == nil && c.baseURL == defaultBaseURL
+}
+
+// String returns the name of the model
+func (m Model) String() string {
+	return string(m)
+}
@@ -0,0 +1,87 @@
+package gpt3_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestModelValid(t *testing.T) {
+	type testCase struct {
+		model gpt3.Model
+		valid bool
+	}
+	testCases := []testCase{
+		{gpt3.TextDavinci003Engine, true},
+		{gpt3.DefaultEngine, true},
+		{gpt3.ContentFilterEngine, true},
+		{gpt3.GPT3Dot5Turbo, true},
+		{gpt3.TextEmbeddingAda002, true},
+		{gpt3.Whisper1, true},
+		{"gpt-4-turbo", true},
+		{"gpt-4-0613", true},
+		{"gpt-4-turbo-2024-04-09", true},
+		{"gpt-3.5-turbo-16k-0613", true},
+		{"gpt-4-trubo-0613", false},
+		{"gpt-4-1106-vision-preview", false},
+		{"ft:gpt-3.5-turbo-0613:my-org::7p4lURel", true},
+		{"curie:ft-my-org-2023-01-01-00-00-00", true},
+		{"text-davinci-03", false},
+		{"gpt-4-trubo", false},
+		{"", false},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.model), func(t *testing.T) {
+			assert.Equal(t, tc.valid, tc.model.Valid())
+		})
+	}
+}
+
+func TestModelValidation(t *testing.T) {
+	ctx := context.Background()
+	rt, httpClient := fakeHttpClient()
+	rt.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(`{"choices":[]}`))}, nil
+	}
+	client := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient))
+	request := gpt3.CompletionRequest{Prompt: []string{"test"}}
+
+	_, err := client.CompletionWithEngine(ctx, "text-davinci-03", request)
+	assert.True(t, errors.Is(err, gpt3.ErrUnknownModel))
+	assert.EqualError(t, err, `unknown model "text-davinci-03", use WithModelValidation(false) to send it anyway`)
+	_, err = client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{Model: "gpt-4-trubo"})
+	assert.True(t, errors.Is(err, gpt3.ErrUnknownModel))
+	_, err = client.Embeddings(ctx, gpt3.EmbeddingsRequest{Model: "text-embedding-ada-02"})
+	assert.True(t, errors.Is(err, gpt3.ErrUnknownModel))
+	_, err = client.EmbeddingsBatch(ctx, "text-embedding-ada-02", []string{"test"})
+	assert.True(t, errors.Is(err, gpt3.ErrUnknownModel))
+	typo := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), gpt3.WithDefaultEngine("davinc"))
+	_, err = typo.Completion(ctx, request)
+	assert.True(t, errors.Is(err, gpt3.ErrUnknownModel))
+	assert.Equal(t, 0, rt.RoundTripCallCount())
+
+	// fine-tuned models and snapshots are sent
+	_, err = client.CompletionWithEngine(ctx, "curie:ft-acme-2023-01-01-00-00-00", request)
+	assert.NoError(t, err)
+	_, err = client.ChatCompletion(ctx, gpt3.ChatCompletionRequest{Model: "gpt-4-0613"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rt.RoundTripCallCount())
+
+	// as are unknown models when validation is disabled, or for other servers
+	for _, option := range []gpt3.ClientOption{
+		gpt3.WithModelValidation(false),
+		gpt3.WithBaseURL("http://localhost:8000/v1"),
+		gpt3.WithAzure("resource", "deployment", "2023-05-15"),
+	} {
+		other := gpt3.NewClient("test-key", gpt3.WithHTTPClient(httpClient), option)
+		_, err = other.CompletionWithEngine(ctx, "my-model", request)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 5, rt.RoundTripCallCount())
+}
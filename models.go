@@ -1,15 +1,40 @@
 package gpt3
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
 
 // APIError represents an error that occured on an API
 type APIError struct {
 	StatusCode int    `json:"status_code"`
 	Message    string `json:"message"`
 	Type       string `json:"type"`
+	// Param is the request parameter that caused the error, if any
+	Param string `json:"param"`
+	// Code identifies the error for matching in code, such as "invalid_api_key", if the API returned one
+	Code string `json:"code"`
+	// RequestID is the id of the request from the x-request-id header, which OpenAI support asks for when
+	// investigating failures
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func (e APIError) Error() string {
+	var details []string
+	if e.Code != "" {
+		details = append(details, "code "+e.Code)
+	}
+	if e.RequestID != "" {
+		details = append(details, "request id "+e.RequestID)
+	}
+	if len(details) > 0 {
+		return fmt.Sprintf("[%d:%s] %s (%s)", e.StatusCode, e.Type, e.Message, strings.Join(details, ", "))
+	}
 	return fmt.Sprintf("[%d:%s] %s", e.StatusCode, e.Type, e.Message)
 }
 
@@ -18,6 +43,20 @@ type APIErrorResponse struct {
 	Error APIError `json:"error"`
 }
 
+// ResponseHeaders holds the HTTP headers of the response that a value was decoded from, such as the
+// x-ratelimit-remaining-requests and x-ratelimit-reset-tokens rate limit headers. It's embedded in the responses of
+// the completion, list and other top-level requests, and Header is nil for values that weren't decoded from a response
+// by the client. Objects that are also elements of lists, such as ModelObject, FileObject and FineTune, and the
+// responses of deletes don't embed it, so they compare equal however they were decoded.
+type ResponseHeaders struct {
+	Header http.Header `json:"-"`
+}
+
+// setHeader is used by the client to set the headers of a decoded response
+func (r *ResponseHeaders) setHeader(header http.Header) {
+	r.Header = header
+}
+
 // EngineObject contained in an engine reponse
 type EngineObject struct {
 	ID     string `json:"id"`
@@ -30,15 +69,157 @@ type EngineObject struct {
 type EnginesResponse struct {
 	Data   []EngineObject `json:"data"`
 	Object string         `json:"object"`
+	ResponseHeaders
+}
+
+// Ready returns the engines that are ready to be used
+func (r *EnginesResponse) Ready() []EngineObject {
+	var ready []EngineObject
+	for _, engine := range r.Data {
+		if engine.Ready {
+			ready = append(ready, engine)
+		}
+	}
+	return ready
+}
+
+// ByID returns the engine with the given id, and whether it was found
+func (r *EnginesResponse) ByID(id string) (*EngineObject, bool) {
+	for i := range r.Data {
+		if r.Data[i].ID == id {
+			return &r.Data[i], true
+		}
+	}
+	return nil, false
+}
+
+// ModelPermission describes what a model may be used for
+type ModelPermission struct {
+	ID                 string  `json:"id"`
+	Object             string  `json:"object"`
+	Created            int     `json:"created"`
+	AllowCreateEngine  bool    `json:"allow_create_engine"`
+	AllowSampling      bool    `json:"allow_sampling"`
+	AllowLogprobs      bool    `json:"allow_logprobs"`
+	AllowSearchIndices bool    `json:"allow_search_indices"`
+	AllowView          bool    `json:"allow_view"`
+	AllowFineTuning    bool    `json:"allow_fine_tuning"`
+	Organization       string  `json:"organization"`
+	Group              *string `json:"group"`
+	IsBlocking         bool    `json:"is_blocking"`
+}
+
+// ModelObject contained in a models response
+type ModelObject struct {
+	ID         string            `json:"id"`
+	Object     string            `json:"object"`
+	Created    int               `json:"created"`
+	OwnedBy    string            `json:"owned_by"`
+	Permission []ModelPermission `json:"permission"`
+	Root       string            `json:"root"`
+	Parent     *string           `json:"parent"`
+}
+
+// ModelsResponse is returned from the Models API
+type ModelsResponse struct {
+	Data   []ModelObject `json:"data"`
+	Object string        `json:"object"`
+	ResponseHeaders
+}
+
+// ByID returns the model with the given id, and whether it was found
+func (r *ModelsResponse) ByID(id string) (*ModelObject, bool) {
+	for i := range r.Data {
+		if r.Data[i].ID == id {
+			return &r.Data[i], true
+		}
+	}
+	return nil, false
 }
 
+// DeleteModelResponse is returned from the delete model API
+type DeleteModelResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+// Chat message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Reasons a choice finished, from the FinishReason of choices
+const (
+	// FinishReasonStop is when the model finished naturally or hit a stop sequence
+	FinishReasonStop = "stop"
+	// FinishReasonLength is when the output was cut off by max tokens or the context length
+	FinishReasonLength = "length"
+	// FinishReasonContentFilter is when content was omitted by the content filter
+	FinishReasonContentFilter = "content_filter"
+	// FinishReasonToolCalls is when the model called a tool
+	FinishReasonToolCalls = "tool_calls"
+)
+
 // ChatCompletionRequestMessage is a message to use as the context for the chat completion API
 type ChatCompletionRequestMessage struct {
-	// Role is the role is the role of the the message. Can be "system", "user", or "assistant"
+	// Role is the role of the the message. One of RoleSystem, RoleUser, RoleAssistant or RoleTool
 	Role string `json:"role"`
 
 	// Content is the content of the message
 	Content string `json:"content"`
+
+	// Name is the name of the author of the message
+	Name string `json:"name,omitempty"`
+
+	// ToolCalls are the tool calls made by the model, when passing an assistant message back in the history
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// ToolCallID is the ID of the tool call that a "tool" message is the result of
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// ToolTypeFunction is the only type of tool currently supported
+const ToolTypeFunction = "function"
+
+// Tool is a tool the model may call
+type Tool struct {
+	// Type is the type of the tool, ToolTypeFunction
+	Type string `json:"type"`
+
+	// Function describes the function that can be called
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a function the model may call
+type FunctionDefinition struct {
+	// Name is the name of the function to be called
+	Name string `json:"name"`
+
+	// Description is what the function does, used by the model to choose when and how to call it
+	Description string `json:"description,omitempty"`
+
+	// Parameters is the JSON schema of the arguments the function accepts
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a call to a tool chosen by the model
+type ToolCall struct {
+	// Index is the position of the tool call among the tool calls of the message, only set on the fragments of tool
+	// calls in the deltas of a stream. ToolCallAccumulator reassembles them into whole tool calls.
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall is the name of the function the model called, and the arguments to call it with as JSON
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // ChatCompletionRequest is a request for the chat completion API
@@ -62,7 +243,7 @@ type ChatCompletionRequest struct {
 	Stream bool `json:"stream,omitempty"`
 
 	// Up to 4 sequences where the API will stop generating further tokens.
-	Stop []string `json:"stop,omitempty"`
+	Stop StopSequences `json:"stop,omitempty"`
 
 	// MaxTokens is the maximum number of tokens to return.
 	MaxTokens int `json:"max_tokens,omitempty"`
@@ -76,8 +257,90 @@ type ChatCompletionRequest struct {
 	// Modify the probability of specific tokens appearing in the completion.
 	LogitBias map[string]float32 `json:"logit_bias,omitempty"`
 
+	// Logprobs returns the log probabilities of the tokens of each choice in its Logprobs
+	Logprobs *bool `json:"logprobs,omitempty"`
+
+	// TopLogprobs is the number of most likely tokens, between 0 and 20, to return with the log probability of each
+	// token. Logprobs must be enabled to use it.
+	TopLogprobs *int `json:"top_logprobs,omitempty"`
+
 	// Can be used to identify an end-user
 	User string `json:"user,omitempty"`
+
+	// ResponseFormat forces the format of the response, such as JSON mode. When using ResponseFormatTypeJSONObject
+	// the API requires that one of the messages instructs the model to produce JSON.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Tools are the tools the model may call
+	Tools []Tool `json:"tools,omitempty"`
+
+	// ToolChoice controls which tool is called. Either "none", "auto", or an object naming a function such as
+	// map[string]interface{}{"type": "function", "function": map[string]string{"name": "my_function"}}
+	ToolChoice interface{} `json:"tool_choice,omitempty"`
+
+	// Seed makes sampling deterministic on a best effort basis, so repeated requests with the same seed and
+	// parameters should return the same result. Compare the SystemFingerprint of responses to detect backend changes
+	// that affect determinism.
+	Seed *int `json:"seed,omitempty"`
+
+	// StreamOptions are options for ChatCompletionStream, and are ignored by ChatCompletion
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// StreamOptions are the options of a streamed chat completion
+type StreamOptions struct {
+	// IncludeUsage sends the token usage of the whole request in an extra chunk at the end of the stream, which has
+	// no choices
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// StopSequences are the sequences where the API stops generating tokens. A single sequence is sent as a string and
+// several as an array, and they are left out of the request when empty, including an empty non-nil slice. When
+// decoding, a single string is accepted as well as an array, like the API does.
+type StopSequences []string
+
+// StopString returns the StopSequences of a single sequence
+func StopString(stop string) StopSequences {
+	return StopSequences{stop}
+}
+
+// MarshalJSON encodes a single sequence as a string and several as an array
+func (s StopSequences) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+// UnmarshalJSON decodes a string or an array of strings
+func (s *StopSequences) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = nil
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = StopSequences{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("stop must be a string or an array of strings: %v", err)
+	}
+	*s = multiple
+	return nil
+}
+
+// ResponseFormat types
+const (
+	ResponseFormatTypeText       = "text"
+	ResponseFormatTypeJSONObject = "json_object"
+)
+
+// ResponseFormat is the format a chat completion must be returned in
+type ResponseFormat struct {
+	// Type is one of the ResponseFormatType constants
+	Type string `json:"type"`
 }
 
 // CompletionRequest is a request for the completions API
@@ -92,17 +355,28 @@ type CompletionRequest struct {
 	// Alternative to temperature for nucleus sampling
 	TopP *float32 `json:"top_p,omitempty"`
 	// How many choice to create for each prompt
-	N *int `json:"n"`
+	N *int `json:"n,omitempty"`
+	// Generates best_of completions server-side and returns the "best" (the one with the highest log probability
+	// per token). Must be greater than or equal to N, and can't be used when streaming. Every one of the best_of
+	// completions is billed, so values above ExpensiveBestOf also require AllowExpensiveBestOf.
+	BestOf *int `json:"best_of,omitempty"`
+	// AllowExpensiveBestOf opts in to a BestOf above ExpensiveBestOf, which Validate rejects otherwise. It's not sent
+	// to the API.
+	AllowExpensiveBestOf bool `json:"-"`
+	// The suffix that comes after a completion of inserted text
+	Suffix string `json:"suffix,omitempty"`
 	// Include the probabilities of most likely tokens
 	LogProbs *int `json:"logprobs"`
-	// Echo back the prompt in addition to the completion
+	// Echo back the prompt in addition to the completion. The echoed prompt is included at the start of each choice's
+	// Text, and when streaming it can arrive split over several chunks; EchoLength on the returned choices marks how
+	// much of the text is the echoed prompt so GeneratedText returns only the completion.
 	Echo bool `json:"echo"`
 	// Up to 4 sequences where the API will stop generating tokens. Response will not contain the stop sequence.
-	Stop []string `json:"stop,omitempty"`
-	// PresencePenalty number between 0 and 1 that penalizes tokens that have already appeared in the text so far.
-	PresencePenalty float32 `json:"presence_penalty"`
-	// FrequencyPenalty number between 0 and 1 that penalizes tokens on existing frequency in the text so far.
-	FrequencyPenalty float32 `json:"frequency_penalty"`
+	Stop StopSequences `json:"stop,omitempty"`
+	// PresencePenalty number between -2.0 and 2.0 that penalizes tokens that have already appeared in the text so far.
+	PresencePenalty *float32 `json:"presence_penalty,omitempty"`
+	// FrequencyPenalty number between -2.0 and 2.0 that penalizes tokens on existing frequency in the text so far.
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
 
 	// Whether to stream back results or not. Don't set this value in the request yourself
 	// as it will be overriden depending on if you use CompletionStream or Completion methods.
@@ -110,7 +384,52 @@ type CompletionRequest struct {
 
 	// Pass a uniqueID for every user w/ each API call (both for Completion & the Content Filter) e.g. user= $uniqueID.
 	// This 'user' param can be passed in the request body along with other params such as prompt, max_tokens etc.
-	User string `json:"user"`
+	User string `json:"user,omitempty"`
+
+	// Seed makes sampling deterministic on a best effort basis, so repeated requests with the same seed and
+	// parameters should return the same result. Compare the SystemFingerprint of responses to detect backend changes
+	// that affect determinism.
+	Seed *int `json:"seed,omitempty"`
+}
+
+// ExpensiveBestOf is the largest CompletionRequest.BestOf allowed without setting AllowExpensiveBestOf, since each of
+// the best_of completions costs as much as a completion of its own
+const ExpensiveBestOf = 5
+
+// Validate checks the request for values the API would reject, returning an error naming the first invalid field.
+// It is called by the completion methods before a request is sent.
+func (r CompletionRequest) Validate() error {
+	if len(r.Prompt) == 0 {
+		return errors.New("prompt must have at least one prompt")
+	}
+	if r.MaxTokens != nil && *r.MaxTokens <= 0 {
+		return fmt.Errorf("max_tokens must be greater than 0, got %d", *r.MaxTokens)
+	}
+	if len(r.Stop) > 4 {
+		return fmt.Errorf("stop can have at most 4 sequences, got %d", len(r.Stop))
+	}
+	if r.Temperature != nil && (*r.Temperature < 0 || *r.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %v", *r.Temperature)
+	}
+	if r.TopP != nil && (*r.TopP < 0 || *r.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1, got %v", *r.TopP)
+	}
+	if r.N != nil && *r.N < 1 {
+		return fmt.Errorf("n must be at least 1, got %d", *r.N)
+	}
+	if r.BestOf != nil {
+		if *r.BestOf < 1 {
+			return fmt.Errorf("best_of must be at least 1, got %d", *r.BestOf)
+		}
+		if r.N != nil && *r.BestOf < *r.N {
+			return fmt.Errorf("best_of must be greater than or equal to n, got best_of %d and n %d", *r.BestOf, *r.N)
+		}
+		if *r.BestOf > ExpensiveBestOf && !r.AllowExpensiveBestOf {
+			return fmt.Errorf("best_of of %d bills %d completions for each prompt, set AllowExpensiveBestOf to allow "+
+				"more than %d", *r.BestOf, *r.BestOf, ExpensiveBestOf)
+		}
+	}
+	return validatePenalties(r.PresencePenalty, r.FrequencyPenalty)
 }
 
 // EditsRequest is a request for the edits API
@@ -126,7 +445,7 @@ type EditsRequest struct {
 	// Alternative to temperature for nucleus sampling
 	TopP *float32 `json:"top_p,omitempty"`
 	// How many edits to generate for the input and instruction. Defaults to 1
-	N *int `json:"n"`
+	N *int `json:"n,omitempty"`
 }
 
 // EmbeddingsRequest is a request for the Embeddings API
@@ -146,8 +465,9 @@ type EmbeddingsRequest struct {
 	User string `json:"user,omitempty"`
 }
 
-// LogprobResult represents logprob result of Choice
-type LogprobResult struct {
+// LogProbResult holds the log probabilities of the tokens in a choice. It is only populated when
+// CompletionRequest.LogProbs is set.
+type LogProbResult struct {
 	Tokens        []string             `json:"tokens"`
 	TokenLogprobs []float32            `json:"token_logprobs"`
 	TopLogprobs   []map[string]float32 `json:"top_logprobs"`
@@ -156,8 +476,32 @@ type LogprobResult struct {
 
 // ChatCompletionResponseMessage is a message returned in the response to the Chat Completions API
 type ChatCompletionResponseMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ChatLogprobs are the log probabilities of the tokens of a chat completion choice
+type ChatLogprobs struct {
+	Content []TokenLogprob `json:"content"`
+}
+
+// TokenLogprob is the log probability of a token, along with the most likely tokens in its place when
+// ChatCompletionRequest.TopLogprobs is set
+type TokenLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float32 `json:"logprob"`
+	// Bytes is the UTF-8 encoding of the token, which can be nil. A character can be split over several tokens, so
+	// the bytes of consecutive tokens may need to be joined to decode it.
+	Bytes       []int        `json:"bytes"`
+	TopLogprobs []TopLogprob `json:"top_logprobs"`
+}
+
+// TopLogprob is one of the most likely tokens in the place of a token, and its log probability
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float32 `json:"logprob"`
+	Bytes   []int   `json:"bytes"`
 }
 
 // ChatCompletionResponseChoice is one of the choices returned in the response to the Chat Completions API
@@ -165,21 +509,24 @@ type ChatCompletionResponseChoice struct {
 	Index        int                           `json:"index"`
 	FinishReason string                        `json:"finish_reason"`
 	Message      ChatCompletionResponseMessage `json:"message"`
+	// Logprobs is set when ChatCompletionRequest.Logprobs is enabled
+	Logprobs *ChatLogprobs `json:"logprobs,omitempty"`
 }
 
-// ChatCompletionResponseChoice is one of the choices returned in the response to the Chat Completions API
+// ChatCompletionStreamResponseChoice is one of the choices returned in a streamed chunk from the Chat Completions
+// API. Delta only holds the Role, Content and/or fragments of ToolCalls that were added since the previous chunk.
 type ChatCompletionStreamResponseChoice struct {
 	Index        int                           `json:"index"`
 	FinishReason string                        `json:"finish_reason"`
 	Delta        ChatCompletionResponseMessage `json:"delta"`
+	// Logprobs holds the log probabilities of the tokens in Delta when ChatCompletionRequest.Logprobs is enabled
+	Logprobs *ChatLogprobs `json:"logprobs,omitempty"`
 }
 
-// ChatCompletionsResponseUsage is the object that returns how many tokens the completion's request used
-type ChatCompletionsResponseUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
-}
+// ChatCompletionsResponseUsage is the object that returns how many tokens the completion's request used.
+//
+// Deprecated: it's the same type as Usage, which is used by all of the completion APIs.
+type ChatCompletionsResponseUsage = Usage
 
 // ChatCompletionResponse is the full response from a request to the Chat Completions API
 type ChatCompletionResponse struct {
@@ -188,24 +535,56 @@ type ChatCompletionResponse struct {
 	Created int                            `json:"created"`
 	Model   string                         `json:"model"`
 	Choices []ChatCompletionResponseChoice `json:"choices"`
-	Usage   ChatCompletionsResponseUsage   `json:"usage"`
+	Usage   Usage                          `json:"usage"`
+	// SystemFingerprint identifies the backend configuration the request ran with
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	ResponseHeaders
 }
 
+// ChatCompletionStreamResponse is a single chunk streamed back from a request to the Chat Completions API
 type ChatCompletionStreamResponse struct {
 	ID      string                               `json:"id"`
 	Object  string                               `json:"object"`
 	Created int                                  `json:"created"`
 	Model   string                               `json:"model"`
 	Choices []ChatCompletionStreamResponseChoice `json:"choices"`
-	Usage   ChatCompletionsResponseUsage         `json:"usage"`
+	// Usage is only set on the last chunk, when StreamOptions.IncludeUsage is set. That chunk has no choices and is
+	// returned by ChatCompletionStreamWithUsage rather than passed to onData.
+	Usage *Usage `json:"usage,omitempty"`
+	// SystemFingerprint identifies the backend configuration the request ran with
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
 }
 
 // CompletionResponseChoice is one of the choices returned in the response to the Completions API
 type CompletionResponseChoice struct {
-	Text         string        `json:"text"`
-	Index        int           `json:"index"`
-	LogProbs     LogprobResult `json:"logprobs"`
-	FinishReason string        `json:"finish_reason"`
+	Text         string         `json:"text"`
+	Index        int            `json:"index"`
+	LogProbs     *LogProbResult `json:"logprobs"`
+	FinishReason string         `json:"finish_reason"`
+	// EchoLength is the number of bytes at the start of Text that are the echoed prompt, set by the completion
+	// methods when the request has Echo enabled. It's not part of the API response.
+	EchoLength int `json:"-"`
+	// StopReason is the stop sequence, or the token id of the stop token, that ended the choice. OpenAI doesn't
+	// return it, but some compatible servers, such as vLLM, do.
+	StopReason interface{} `json:"stop_reason,omitempty"`
+	// MatchedStop is the stop sequence of the request that ended a streamed choice, set on its final chunk when its
+	// FinishReason is FinishReasonStop. The API leaves the matched sequence out of the text, so it's worked out from
+	// the request's Stop: it's the only sequence when there is one, otherwise the StopReason reported by servers that
+	// send one, and empty when neither tells which matched, as with several sequences on OpenAI. The API also reports
+	// FinishReasonStop when the model ends the text by itself, which can't be told apart, so with a single sequence
+	// MatchedStop is set then too. It's empty when the choice was cut off by the length limit.
+	MatchedStop string `json:"-"`
+}
+
+// GeneratedText returns Text without the echoed prompt
+func (c CompletionResponseChoice) GeneratedText() string {
+	if c.EchoLength <= 0 {
+		return c.Text
+	}
+	if c.EchoLength >= len(c.Text) {
+		return ""
+	}
+	return c.Text[c.EchoLength:]
 }
 
 // CompletionResponse is the full response from a request to the completions API
@@ -215,11 +594,46 @@ type CompletionResponse struct {
 	Created int                        `json:"created"`
 	Model   string                     `json:"model"`
 	Choices []CompletionResponseChoice `json:"choices"`
-	Usage   CompletionResponseUsage    `json:"usage"`
+	// Usage is nil for streamed responses which don't report token usage
+	Usage *Usage `json:"usage,omitempty"`
+	// SystemFingerprint identifies the backend configuration the request ran with
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	ResponseHeaders
 }
 
-// CompletionResponseUsage is the object that returns how many tokens the completion's request used
-type CompletionResponseUsage struct {
+// FirstChoiceText returns the text of the first choice and true, or "" and false when there are no choices. Streams can
+// include chunks without any choices, such as keep-alives, so it's a safe alternative to Choices[0].Text in stream
+// callbacks.
+func (r *CompletionResponse) FirstChoiceText() (string, bool) {
+	if len(r.Choices) == 0 {
+		return "", false
+	}
+	return r.Choices[0].Text, true
+}
+
+// ChoicesByPrompt groups the choices by the prompt they were generated for, where n is the N of the request. The API
+// orders choices prompt-major, so the choice with Index i is choice i%n of prompt i/n. The result has one slice per
+// prompt in prompt order, each ordered by index. A prompt without any choices has a nil slice.
+func (r *CompletionResponse) ChoicesByPrompt(n int) [][]CompletionResponseChoice {
+	if n < 1 {
+		n = 1
+	}
+	var grouped [][]CompletionResponseChoice
+	for _, choice := range r.Choices {
+		prompt := choice.Index / n
+		for len(grouped) <= prompt {
+			grouped = append(grouped, nil)
+		}
+		grouped[prompt] = append(grouped[prompt], choice)
+	}
+	for _, choices := range grouped {
+		sort.SliceStable(choices, func(i, j int) bool { return choices[i].Index < choices[j].Index })
+	}
+	return grouped
+}
+
+// Usage is the object that returns how many tokens a request used
+type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
@@ -230,16 +644,23 @@ type EditsResponse struct {
 	Object  string                `json:"object"`
 	Created int                   `json:"created"`
 	Choices []EditsResponseChoice `json:"choices"`
-	Usage   EditsResponseUsage    `json:"usage"`
+	Usage   Usage                 `json:"usage"`
+	ResponseHeaders
 }
 
-// The inner result of a create embeddings request, containing the embeddings for a single input.
-type EmbeddingsResult struct {
+// Embedding is the inner result of a create embeddings request, containing the embedding for a single input.
+type Embedding struct {
 	// The type of object returned (e.g., "list", "object")
 	Object string `json:"object"`
-	// The embedding data for the input
-	Embedding []float64 `json:"embedding"`
-	Index     int       `json:"index"`
+	// The embedding vector for the input
+	Embedding []float32 `json:"embedding"`
+	// Index of the input this embedding belongs to
+	Index int `json:"index"`
+}
+
+// SimilarityTo returns the cosine similarity between this embedding and another
+func (e Embedding) SimilarityTo(other Embedding) (float32, error) {
+	return CosineSimilarity(e.Embedding, other.Embedding)
 }
 
 // The usage stats for an embeddings response
@@ -254,9 +675,10 @@ type EmbeddingsUsage struct {
 //
 // See: https://beta.openai.com/docs/api-reference/embeddings/create
 type EmbeddingsResponse struct {
-	Object string             `json:"object"`
-	Data   []EmbeddingsResult `json:"data"`
-	Usage  EmbeddingsUsage    `json:"usage"`
+	Object string          `json:"object"`
+	Data   []Embedding     `json:"data"`
+	Usage  EmbeddingsUsage `json:"usage"`
+	ResponseHeaders
 }
 
 // EditsResponseChoice is one of the choices returned in the response to the Edits API
@@ -265,17 +687,317 @@ type EditsResponseChoice struct {
 	Index int    `json:"index"`
 }
 
-// EditsResponseUsage is a structure used in the response from a request to the edits API
-type EditsResponseUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+// EditsResponseUsage is a structure used in the response from a request to the edits API.
+//
+// Deprecated: it's the same type as Usage, which is used by all of the completion APIs.
+type EditsResponseUsage = Usage
+
+// Moderation categories returned in ModerationResult.Categories and ModerationResult.CategoryScores
+const (
+	ModerationCategoryHate            = "hate"
+	ModerationCategoryHateThreatening = "hate/threatening"
+	ModerationCategorySelfHarm        = "self-harm"
+	ModerationCategorySexual          = "sexual"
+	ModerationCategorySexualMinors    = "sexual/minors"
+	ModerationCategoryViolence        = "violence"
+	ModerationCategoryViolenceGraphic = "violence/graphic"
+)
+
+// ModerationRequest is a request for the moderations API
+type ModerationRequest struct {
+	// The input text to classify
+	Input []string `json:"input"`
+	// ID of the model to use. Defaults to text-moderation-latest when empty.
+	Model string `json:"model,omitempty"`
+}
+
+// ModerationResult is the classification of a single input
+type ModerationResult struct {
+	// Flagged is true if the input violates OpenAI's content policy in any of the categories
+	Flagged bool `json:"flagged"`
+	// Categories maps each category to whether the input was flagged for it
+	Categories map[string]bool `json:"categories"`
+	// CategoryScores maps each category to the model's confidence that the input violates it
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
+
+// ModerationResponse is the full response from a request to the moderations API
+type ModerationResponse struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+	ResponseHeaders
+}
+
+// AnyFlagged returns true if any of the inputs were flagged
+func (r *ModerationResponse) AnyFlagged() bool {
+	for _, result := range r.Results {
+		if result.Flagged {
+			return true
+		}
+	}
+	return false
+}
+
+// Image sizes supported by the image APIs
+const (
+	ImageSize256x256   = "256x256"
+	ImageSize512x512   = "512x512"
+	ImageSize1024x1024 = "1024x1024"
+)
+
+// Image response formats supported by the image APIs
+const (
+	ImageResponseFormatURL     = "url"
+	ImageResponseFormatB64JSON = "b64_json"
+)
+
+// ImageRequest is a request for the image generation API
+type ImageRequest struct {
+	// A text description of the desired image(s). The maximum length is 1000 characters.
+	Prompt string `json:"prompt"`
+	// The number of images to generate. Must be between 1 and 10. Defaults to 1
+	N *int `json:"n,omitempty"`
+	// The size of the generated images. Must be one of the ImageSize constants. Defaults to 1024x1024
+	Size string `json:"size,omitempty"`
+	// The format in which the generated images are returned, "url" or "b64_json". Defaults to "url"
+	ResponseFormat string `json:"response_format,omitempty"`
+	// A unique identifier representing your end-user, which can help OpenAI to monitor and detect abuse.
+	User string `json:"user,omitempty"`
+}
+
+// ImageEditRequest is a request for the image edit API. It is sent as multipart/form-data.
+type ImageEditRequest struct {
+	// The image to edit. Must be a valid square PNG file, less than 4MB. If Mask is not provided, the image
+	// must have transparency which will be used as the mask.
+	Image io.Reader
+	// An optional PNG whose fully transparent areas indicate where Image should be edited. Must have the
+	// same dimensions as Image.
+	Mask io.Reader
+	// A text description of the desired image(s). The maximum length is 1000 characters.
+	Prompt string
+	// The number of images to generate. Must be between 1 and 10. Defaults to 1
+	N *int
+	// The size of the generated images. Must be one of the ImageSize constants. Defaults to 1024x1024
+	Size string
+	// The format in which the generated images are returned, "url" or "b64_json". Defaults to "url"
+	ResponseFormat string
+	// A unique identifier representing your end-user, which can help OpenAI to monitor and detect abuse.
+	User string
+}
+
+// ImageVariationRequest is a request for the image variation API. It is sent as multipart/form-data.
+type ImageVariationRequest struct {
+	// The image to use as the basis for the variation(s). Must be a valid square PNG file, less than 4MB.
+	Image io.Reader
+	// The number of images to generate. Must be between 1 and 10. Defaults to 1
+	N *int
+	// The size of the generated images. Must be one of the ImageSize constants. Defaults to 1024x1024
+	Size string
+	// The format in which the generated images are returned, "url" or "b64_json". Defaults to "url"
+	ResponseFormat string
+	// A unique identifier representing your end-user, which can help OpenAI to monitor and detect abuse.
+	User string
+}
+
+// ImageData is a single generated image. Only one of URL or B64JSON is set depending on the
+// requested response format.
+type ImageData struct {
+	URL     string `json:"url,omitempty"`
+	B64JSON string `json:"b64_json,omitempty"`
+}
+
+// ImageResponse is the full response from a request to the image APIs
+type ImageResponse struct {
+	Created int         `json:"created"`
+	Data    []ImageData `json:"data"`
+	ResponseHeaders
+}
+
+// Audio response formats supported by the audio APIs
+const (
+	AudioResponseFormatJSON        = "json"
+	AudioResponseFormatText        = "text"
+	AudioResponseFormatSRT         = "srt"
+	AudioResponseFormatVerboseJSON = "verbose_json"
+	AudioResponseFormatVTT         = "vtt"
+)
+
+// AudioRequest is a request for the audio APIs. It is sent as multipart/form-data.
+type AudioRequest struct {
+	// The audio file to transcribe, in one of these formats: mp3, mp4, mpeg, mpga, m4a, wav, or webm.
+	File io.Reader
+	// FileName is the name of the audio file. Its extension is used by the API to detect the audio format.
+	FileName string
+	// ID of the model to use. Defaults to whisper-1 which is currently the only model available.
+	Model string
+	// Optional text to guide the model's style or continue a previous audio segment.
+	// The prompt should match the audio language.
+	Prompt string
+	// The format of the transcript output, one of the AudioResponseFormat constants. Defaults to json.
+	// Only the json formats decode into AudioResponse fields, the others are returned as-is in Text.
+	ResponseFormat string
+	// The sampling temperature, between 0 and 1.
+	Temperature *float32
+	// The ISO-639-1 language of the input audio. Supplying it improves accuracy and latency.
+	// Only used for transcriptions.
+	Language string
+}
+
+// AudioSegment is a timestamped segment of a transcript, only returned with the verbose_json format
+type AudioSegment struct {
+	ID               int     `json:"id"`
+	Seek             int     `json:"seek"`
+	Start            float64 `json:"start"`
+	End              float64 `json:"end"`
+	Text             string  `json:"text"`
+	Tokens           []int   `json:"tokens"`
+	Temperature      float64 `json:"temperature"`
+	AvgLogprob       float64 `json:"avg_logprob"`
+	CompressionRatio float64 `json:"compression_ratio"`
+	NoSpeechProb     float64 `json:"no_speech_prob"`
+	Transient        bool    `json:"transient"`
+}
+
+// AudioResponse is the full response from a request to the audio APIs. Task, Language, Duration and
+// Segments are only set with the verbose_json format.
+type AudioResponse struct {
+	Task     string         `json:"task,omitempty"`
+	Language string         `json:"language,omitempty"`
+	Duration float64        `json:"duration,omitempty"`
+	Text     string         `json:"text"`
+	Segments []AudioSegment `json:"segments,omitempty"`
+	ResponseHeaders
+}
+
+// File purposes supported by the files API
+const (
+	FilePurposeFineTune = "fine-tune"
+	FilePurposeSearch   = "search"
+)
+
+// FileRequest is a request to upload a file. It is sent as multipart/form-data.
+type FileRequest struct {
+	// The file to upload. For fine-tuning this must be a JSON Lines file.
+	File io.Reader
+	// FileName is the name the file will be stored under
+	FileName string
+	// The intended purpose of the uploaded file, one of the FilePurpose constants
+	Purpose string
+}
+
+// FileObject describes a file that has been uploaded to OpenAI
+type FileObject struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int    `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+// FilesResponse is returned from the list files API
+type FilesResponse struct {
+	Object string       `json:"object"`
+	Data   []FileObject `json:"data"`
+	ResponseHeaders
+}
+
+// DeleteFileResponse is returned from the delete file API
+type DeleteFileResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+// Fine-tune job statuses
+const (
+	FineTuneStatusPending   = "pending"
+	FineTuneStatusRunning   = "running"
+	FineTuneStatusSucceeded = "succeeded"
+	FineTuneStatusFailed    = "failed"
+	FineTuneStatusCancelled = "cancelled"
+)
+
+// FineTuneRequest is a request for the create fine-tune API
+type FineTuneRequest struct {
+	// The ID of an uploaded file that contains training data
+	TrainingFile string `json:"training_file"`
+	// The ID of an uploaded file that contains validation data
+	ValidationFile string `json:"validation_file,omitempty"`
+	// The name of the base model to fine-tune: ada, babbage, curie or davinci. Defaults to curie
+	Model string `json:"model,omitempty"`
+	// The number of epochs to train the model for. Defaults to 4
+	NEpochs *int `json:"n_epochs,omitempty"`
+	// The batch size to use for training. Defaults to ~0.2% of the number of examples in the training set
+	BatchSize *int `json:"batch_size,omitempty"`
+	// The learning rate multiplier to use for training
+	LearningRateMultiplier *float32 `json:"learning_rate_multiplier,omitempty"`
+	// A string of up to 40 characters that will be added to your fine-tuned model name
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// FineTuneEvent is an event that occurred during a fine-tune job
+type FineTuneEvent struct {
+	Object    string `json:"object"`
+	CreatedAt int    `json:"created_at"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+}
+
+// FineTuneEventsResponse is returned from the list fine-tune events API when not streaming
+type FineTuneEventsResponse struct {
+	Object string          `json:"object"`
+	Data   []FineTuneEvent `json:"data"`
+	ResponseHeaders
+}
+
+// FineTuneHyperparams are the hyperparameters a fine-tune job was run with
+type FineTuneHyperparams struct {
+	BatchSize              int     `json:"batch_size"`
+	LearningRateMultiplier float64 `json:"learning_rate_multiplier"`
+	NEpochs                int     `json:"n_epochs"`
+	PromptLossWeight       float64 `json:"prompt_loss_weight"`
+}
+
+// FineTune describes a fine-tune job
+type FineTune struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Model     string `json:"model"`
+	CreatedAt int    `json:"created_at"`
+	UpdatedAt int    `json:"updated_at"`
+	// Status is one of the FineTuneStatus constants
+	Status string `json:"status"`
+	// FineTunedModel is the name of the resulting model, only set once the job has succeeded
+	FineTunedModel  string              `json:"fine_tuned_model"`
+	OrganizationID  string              `json:"organization_id"`
+	Hyperparams     FineTuneHyperparams `json:"hyperparams"`
+	Events          []FineTuneEvent     `json:"events,omitempty"`
+	TrainingFiles   []FileObject        `json:"training_files"`
+	ValidationFiles []FileObject        `json:"validation_files"`
+	ResultFiles     []FileObject        `json:"result_files"`
+}
+
+// FineTunesResponse is returned from the list fine-tunes API
+type FineTunesResponse struct {
+	Object string     `json:"object"`
+	Data   []FineTune `json:"data"`
+	ResponseHeaders
 }
 
 // SearchRequest is a request for the document search API
 type SearchRequest struct {
-	Documents []string `json:"documents"`
-	Query     string   `json:"query"`
+	// Documents to search over. Either Documents or File can be set, but not both
+	Documents []string `json:"documents,omitempty"`
+	// File is the ID of an uploaded file, with FilePurposeSearch, containing the documents to search over
+	File  string `json:"file,omitempty"`
+	Query string `json:"query"`
+	// MaxRerank is the maximum number of documents to be re-ranked and returned when searching a File
+	MaxRerank *int `json:"max_rerank,omitempty"`
+	// ReturnMetadata returns the metadata of each document in the results when searching a File
+	ReturnMetadata bool `json:"return_metadata,omitempty"`
 }
 
 // SearchData is a single search result from the document search API
@@ -283,10 +1005,15 @@ type SearchData struct {
 	Document int     `json:"document"`
 	Object   string  `json:"object"`
 	Score    float64 `json:"score"`
+	// Text is the text of the document, returned when searching a File
+	Text string `json:"text,omitempty"`
+	// Metadata is the metadata of the document, returned when ReturnMetadata is set
+	Metadata string `json:"metadata,omitempty"`
 }
 
 // SearchResponse is the full response from a request to the document search API
 type SearchResponse struct {
 	Data   []SearchData `json:"data"`
 	Object string       `json:"object"`
+	ResponseHeaders
 }
@@ -0,0 +1,72 @@
+package gpt3
+
+import (
+	"fmt"
+	"sync"
+)
+
+// modelPricing is the price in dollars per 1K tokens of a model
+type modelPricing struct {
+	promptPer1K     float64
+	completionPer1K float64
+}
+
+var (
+	pricingMu sync.RWMutex
+
+	// pricing is the list price of models as published by OpenAI in June 2023. Use SetModelPricing to update or add
+	// to it.
+	pricing = map[string]modelPricing{
+		GPT3Dot5Turbo:        {0.0015, 0.002},
+		GPT3Dot5Turbo0301:    {0.002, 0.002},
+		"gpt-3.5-turbo-16k":  {0.003, 0.004},
+		"gpt-4":              {0.03, 0.06},
+		"gpt-4-32k":          {0.06, 0.12},
+		TextDavinci003Engine: {0.02, 0.02},
+		TextDavinci002Engine: {0.02, 0.02},
+		TextDavinci001Engine: {0.02, 0.02},
+		TextCurie001Engine:   {0.002, 0.002},
+		TextBabbage001Engine: {0.0005, 0.0005},
+		TextAda001Engine:     {0.0004, 0.0004},
+		DavinciEngine:        {0.02, 0.02},
+		CurieEngine:          {0.002, 0.002},
+		BabbageEngine:        {0.0005, 0.0005},
+		AdaEngine:            {0.0004, 0.0004},
+		TextEmbeddingAda002:  {0.0001, 0},
+	}
+)
+
+// SetModelPricing sets the price in dollars per 1K prompt and completion tokens used by CostEstimate for model,
+// overriding the built in price if there is one.
+func SetModelPricing(model string, promptPer1K, completionPer1K float64) {
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+	pricing[model] = modelPricing{promptPer1K: promptPer1K, completionPer1K: completionPer1K}
+}
+
+// CostEstimate returns the estimated cost in dollars of the token usage of a request to model. Dated snapshots such
+// as "gpt-4-0613" or "gpt-4-turbo-2024-04-09" are priced as their base model unless they have a price of their own.
+// An error is returned when the price of model isn't known, including for variants such as "gpt-4-1106-preview"
+// which aren't priced as the model their name starts with.
+func CostEstimate(model string, usage Usage) (float64, error) {
+	price, ok := priceOf(model)
+	if !ok {
+		return 0, fmt.Errorf("no pricing known for model %q", model)
+	}
+	return float64(usage.PromptTokens)/1000*price.promptPer1K +
+		float64(usage.CompletionTokens)/1000*price.completionPer1K, nil
+}
+
+// priceOf returns the price of model, or of the model it is a dated snapshot of
+func priceOf(model string) (modelPricing, bool) {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+	if price, ok := pricing[model]; ok {
+		return price, true
+	}
+	if base, ok := snapshotBase(model); ok {
+		price, ok := pricing[base]
+		return price, ok
+	}
+	return modelPricing{}, false
+}
@@ -0,0 +1,66 @@
+package gpt3_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestCostEstimate(t *testing.T) {
+	type testCase struct {
+		model    string
+		usage    gpt3.Usage
+		expected float64
+	}
+
+	testCases := []testCase{
+		{gpt3.GPT3Dot5Turbo, gpt3.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}, 0.0025},
+		{"gpt-4", gpt3.Usage{PromptTokens: 2000, CompletionTokens: 1000, TotalTokens: 3000}, 0.12},
+		{"gpt-4-0613", gpt3.Usage{PromptTokens: 1000, TotalTokens: 1000}, 0.03},
+		{"gpt-4-32k-0613", gpt3.Usage{PromptTokens: 1000, TotalTokens: 1000}, 0.06},
+		{"gpt-3.5-turbo-16k-2023-06-13", gpt3.Usage{PromptTokens: 1000, TotalTokens: 1000}, 0.003},
+		{gpt3.TextDavinci003Engine, gpt3.Usage{PromptTokens: 250, CompletionTokens: 250, TotalTokens: 500}, 0.01},
+		{gpt3.TextEmbeddingAda002, gpt3.Usage{PromptTokens: 10000, TotalTokens: 10000}, 0.001},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.model, func(t *testing.T) {
+			cost, err := gpt3.CostEstimate(tc.model, tc.usage)
+			assert.NoError(t, err)
+			assert.InDelta(t, tc.expected, cost, 1e-9)
+		})
+	}
+}
+
+func TestCostEstimateUnknownModel(t *testing.T) {
+	for _, model := range []string{"gpt-4-turbo-2024-04-09", "gpt-4-1106-preview", "gpt-4-vision-preview"} {
+		_, err := gpt3.CostEstimate(model, gpt3.Usage{PromptTokens: 10})
+		assert.EqualError(t, err, fmt.Sprintf("no pricing known for model %q", model))
+	}
+
+	_, err := gpt3.CostEstimate("my-custom-model", gpt3.Usage{PromptTokens: 10})
+	assert.EqualError(t, err, `no pricing known for model "my-custom-model"`)
+
+	gpt3.SetModelPricing("my-custom-model", 0.01, 0.03)
+	cost, err := gpt3.CostEstimate("my-custom-model", gpt3.Usage{PromptTokens: 1000, CompletionTokens: 1000})
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.04, cost, 1e-9)
+}
+
+func TestCostEstimateOfResponseUsage(t *testing.T) {
+	// chat and edits responses report the same Usage as completions
+	var chat gpt3.ChatCompletionResponse
+	assert.NoError(t, json.Unmarshal([]byte(`{"usage":{"prompt_tokens":1000,"completion_tokens":500}}`), &chat))
+	cost, err := gpt3.CostEstimate(gpt3.GPT3Dot5Turbo, chat.Usage)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.0025, cost, 1e-9)
+
+	var edits gpt3.EditsResponse
+	assert.NoError(t, json.Unmarshal([]byte(`{"created":1,"usage":{"prompt_tokens":1000,"completion_tokens":1000}}`), &edits))
+	cost, err = gpt3.CostEstimate(gpt3.TextDavinci001Engine, edits.Usage)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.04, cost, 1e-9)
+}
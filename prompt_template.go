@@ -0,0 +1,55 @@
+package gpt3
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// PromptTemplate is a reusable prompt with named variables, written with the text/template syntax, for example:
+//
+//	tmpl := MustPromptTemplate("Translate to {{.language}}: {{.text}}")
+//	prompt, err := tmpl.Render(map[string]string{"language": "French", "text": "Hello"})
+//
+// Rendering fails when the template uses a variable that isn't given, so a typo doesn't silently leave a gap in the
+// prompt. Values are inserted as is, without any escaping. A PromptTemplate is safe for concurrent use.
+type PromptTemplate struct {
+	tmpl *template.Template
+}
+
+// NewPromptTemplate parses text into a PromptTemplate, returning an error when it isn't a valid template
+func NewPromptTemplate(text string) (*PromptTemplate, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return &PromptTemplate{tmpl: tmpl}, nil
+}
+
+// MustPromptTemplate is the same as NewPromptTemplate except it panics when text isn't a valid template, for templates
+// declared as package variables
+func MustPromptTemplate(text string) *PromptTemplate {
+	tmpl, err := NewPromptTemplate(text)
+	if err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+// Render returns the prompt with the variables of the template replaced by their values in vars
+func (t *PromptTemplate) Render(vars map[string]string) (string, error) {
+	var prompt strings.Builder
+	if err := t.tmpl.Execute(&prompt, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return prompt.String(), nil
+}
+
+// MustRender is the same as Render except it panics when the template can't be rendered
+func (t *PromptTemplate) MustRender(vars map[string]string) string {
+	prompt, err := t.Render(vars)
+	if err != nil {
+		panic(err)
+	}
+	return prompt
+}
@@ -0,0 +1,41 @@
+package gpt3_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestPromptTemplate(t *testing.T) {
+	tmpl, err := gpt3.NewPromptTemplate("Translate to {{.language}}: {{.text}}")
+	assert.NoError(t, err)
+
+	prompt, err := tmpl.Render(map[string]string{"language": "French", "text": "<b>Hello</b> & goodbye"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Translate to French: <b>Hello</b> & goodbye", prompt)
+
+	_, err = tmpl.Render(map[string]string{"language": "French"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `map has no entry for key "text"`)
+	assert.Panics(t, func() { tmpl.MustRender(nil) })
+
+	// templates can be rendered concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "Translate to German: Hi", tmpl.MustRender(map[string]string{"language": "German", "text": "Hi"}))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPromptTemplateInvalid(t *testing.T) {
+	_, err := gpt3.NewPromptTemplate("Hello {{.name")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid prompt template")
+	assert.Panics(t, func() { gpt3.MustPromptTemplate("{{end}}") })
+}
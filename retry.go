@@ -0,0 +1,91 @@
+package gpt3
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// isRetryableStatus reports whether a request that failed with the given status code may succeed if sent again.
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// DefaultBackoff returns how long to wait before retrying after the given (zero based) attempt failed, which is the
+// default strategy of WithBackoffStrategy. The delay starts at 500ms, doubles with each attempt up to 30s and is
+// jittered so concurrent clients don't retry in lockstep.
+func DefaultBackoff(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 16 && retryBaseDelay<<attempt < retryMaxDelay {
+		delay = retryBaseDelay << attempt
+	}
+	return time.Duration(random(int64(delay)/2, int64(delay)))
+}
+
+// sleepContext waits for the duration to pass or returns early with the context error if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// randReader is the source of the random idempotency keys
+var randReader = rand.Reader
+
+// newIdempotencyKey returns a random key to identify a request by, which is the default generator of
+// WithIdempotencyKey
+func newIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := io.ReadFull(randReader, key); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// bufferBody makes sure the body of req can be re-read for retries. Bodies created by newRequest already
+// support this, so this only buffers bodies of requests created elsewhere.
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.GetBody != nil {
+		return nil
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// rewindBody resets the body of req so it can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
@@ -0,0 +1,32 @@
+package gpt3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no entropy")
+}
+
+func TestNewIdempotencyKeyError(t *testing.T) {
+	defer func(r io.Reader) { randReader = r }(randReader)
+	randReader = failingReader{}
+
+	c := &client{baseURL: defaultBaseURL, idempotencyKey: newIdempotencyKey}
+	body := strings.NewReader("{}")
+	_, err := c.newRequestWithBody(context.Background(), "POST", "/completions", body, "application/json")
+	if err == nil || err.Error() != "failed to generate idempotency key: no entropy" {
+		t.Fatalf("expected the idempotency key error, got %v", err)
+	}
+
+	// other methods don't need a key
+	if _, err := c.newRequestWithBody(context.Background(), "GET", "/models", nil, "application/json"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
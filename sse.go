@@ -0,0 +1,92 @@
+package gpt3
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"time"
+)
+
+// sseEvent is an event of a server-sent events stream
+type sseEvent struct {
+	// Event is the type of the event, empty for the default "message" type
+	Event string
+	// Data is the payload of the event, with the lines of multi-line data joined by "\n"
+	Data []byte
+	// ID is the last event id sent on the stream
+	ID string
+	// Retry is the reconnection time the server asked for, zero when it hasn't
+	Retry time.Duration
+}
+
+// sseScanner reads the events of a server-sent events stream. Fields are assembled into an event until the blank
+// line ending it, so data spread over several lines is delivered as one payload. Comment lines, starting with a
+// colon, and unknown fields are ignored.
+type sseScanner struct {
+	reader *bufio.Reader
+	id     string
+	retry  time.Duration
+}
+
+func newSSEScanner(r io.Reader) *sseScanner {
+	return &sseScanner{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next event that has data. Events without data are skipped, as the spec requires. The last event
+// is returned even if the stream ends without the blank line ending it, after which io.EOF is returned.
+func (s *sseScanner) Next() (*sseEvent, error) {
+	var (
+		event sseEvent
+		data  [][]byte
+	)
+	for {
+		line, readErr := s.reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		// comment lines, such as the ": keep-alive" lines some proxies send, are skipped without ending the event
+		// they appear in
+		if len(line) > 0 && line[0] != ':' {
+			s.parseField(line, &event, &data)
+		}
+
+		// a blank line ends the event, as does the end of the stream
+		if len(line) == 0 || readErr == io.EOF {
+			if data != nil {
+				event.Data = bytes.Join(data, []byte("\n"))
+				event.ID = s.id
+				event.Retry = s.retry
+				return &event, nil
+			}
+			if readErr == io.EOF {
+				return nil, io.EOF
+			}
+			event = sseEvent{}
+		}
+	}
+}
+
+// parseField adds the field of line to event, or to data for data fields
+func (s *sseScanner) parseField(line []byte, event *sseEvent, data *[][]byte) {
+	field, value := line, []byte(nil)
+	if i := bytes.IndexByte(line, ':'); i >= 0 {
+		field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+	}
+	switch string(field) {
+	case "event":
+		event.Event = string(value)
+	case "data":
+		*data = append(*data, value)
+	case "id":
+		// ids containing a null are ignored
+		if bytes.IndexByte(value, 0) < 0 {
+			s.id = string(value)
+		}
+	case "retry":
+		if ms, err := strconv.Atoi(string(value)); err == nil && ms >= 0 {
+			s.retry = time.Duration(ms) * time.Millisecond
+		}
+	}
+}
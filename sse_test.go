@@ -0,0 +1,83 @@
+package gpt3
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEScanner(t *testing.T) {
+	type testCase struct {
+		name     string
+		stream   string
+		expected []sseEvent
+	}
+
+	testCases := []testCase{
+		{
+			"Single line data",
+			"data: one\n\ndata: two\n\n",
+			[]sseEvent{{Data: []byte("one")}, {Data: []byte("two")}},
+		},
+		{
+			"Multi line data",
+			"data: {\"a\":\ndata: 1}\n\ndata:two\n\n",
+			[]sseEvent{{Data: []byte("{\"a\":\n1}")}, {Data: []byte("two")}},
+		},
+		{
+			"All fields",
+			"event: delta\nid: 7\nretry: 1500\ndata: one\n\nevent: done\ndata: two\n\n",
+			[]sseEvent{
+				{Event: "delta", ID: "7", Retry: 1500 * time.Millisecond, Data: []byte("one")},
+				{Event: "done", ID: "7", Retry: 1500 * time.Millisecond, Data: []byte("two")},
+			},
+		},
+		{
+			"Comments, unknown fields and events without data",
+			": keep-alive\nevent: ping\n\nfoo: bar\ndata: one\n\n",
+			[]sseEvent{{Data: []byte("one")}},
+		},
+		{
+			"Keep-alive comments between and within events",
+			": ping\n\ndata: {\"a\":\n:\n: ping\ndata: 1}\n\n:ping\n\n:\ndata: two\n\n: ping\n",
+			[]sseEvent{{Data: []byte("{\"a\":\n1}")}, {Data: []byte("two")}},
+		},
+		{
+			"Carriage returns",
+			"data: one\r\ndata: two\r\n\r\n",
+			[]sseEvent{{Data: []byte("one\ntwo")}},
+		},
+		{
+			"Unterminated last event",
+			"data: one\n\ndata: two",
+			[]sseEvent{{Data: []byte("one")}, {Data: []byte("two")}},
+		},
+		{
+			"Empty",
+			"",
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner := newSSEScanner(strings.NewReader(tc.stream))
+			var events []sseEvent
+			for {
+				event, err := scanner.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				events = append(events, *event)
+			}
+			if !reflect.DeepEqual(events, tc.expected) {
+				t.Errorf("expected events %q, got %q", tc.expected, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package gpt3
+
+import (
+	"context"
+	"io"
+)
+
+// CompletionStream is a streamed completion returned by CompletionStreamReader. Its chunks are read with Recv, and it
+// must be closed with Close when it's no longer needed.
+type CompletionStream struct {
+	chunks chan *CompletionResponse
+	cancel context.CancelFunc
+	// err is the error that ended the stream, which is set before chunks is closed
+	err error
+}
+
+// Recv returns the next chunk of the completion. It returns io.EOF once the stream has completed, or the error that
+// ended the stream, such as the context error when the context of the stream is done.
+func (s *CompletionStream) Recv() (*CompletionResponse, error) {
+	chunk, ok := <-s.chunks
+	if ok {
+		return chunk, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return nil, io.EOF
+}
+
+// Close stops the stream and releases its connection. Recv returns context.Canceled after the stream is closed,
+// unless the stream had already ended.
+func (s *CompletionStream) Close() error {
+	s.cancel()
+	// wait for the stream to end so the connection is closed before returning
+	for range s.chunks {
+	}
+	return nil
+}
+
+func (c *client) CompletionStreamReader(ctx context.Context, request CompletionRequest) (*CompletionStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	resp, err := c.startCompletionStream(ctx, c.defaultEngine, request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	stream := &CompletionStream{
+		chunks: make(chan *CompletionResponse),
+		cancel: cancel,
+	}
+	decode := completionChunkDecoder(request)
+	go func() {
+		defer close(stream.chunks)
+		stream.err = readStream(ctx, resp.Body, func(data []byte) error {
+			output, err := decode(data)
+			if err != nil {
+				return err
+			}
+			select {
+			case stream.chunks <- output:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return stream, nil
+}
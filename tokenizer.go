@@ -0,0 +1,267 @@
+package gpt3
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+)
+
+//go:embed assets/cl100k_base.tiktoken assets/p50k_base.tiktoken
+var tokenizerAssets embed.FS
+
+const (
+	cl100kBase = "cl100k_base"
+	p50kBase   = "p50k_base"
+	r50kBase   = "r50k_base"
+)
+
+// r50kVocabSize is the number of tokens in r50k_base, which are the same as the first tokens of p50k_base
+const r50kVocabSize = 50257
+
+const (
+	cl100kPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+	p50kPattern   = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+)
+
+// encoding is a byte pair encoding compatible with OpenAI's tiktoken
+type encoding struct {
+	pattern *regexp2.Regexp
+	ranks   map[string]int
+	tokens  map[int]string
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*encoding{}
+)
+
+// CountTokens returns the number of tokens text is encoded to by the tokenizer model uses, so that requests
+// can be sized to fit the model's context. Special tokens such as "<|endoftext|>" are counted as plain text.
+// An error is returned when the tokenizer of model isn't known.
+func CountTokens(model, text string) (int, error) {
+	enc, err := encodingForModel(model)
+	if err != nil {
+		return 0, err
+	}
+	tokens, err := enc.encode(text)
+	if err != nil {
+		return 0, err
+	}
+	return len(tokens), nil
+}
+
+// TruncateSide is the side of a text that TruncateToTokens removes tokens from
+type TruncateSide int
+
+const (
+	// TruncateEnd keeps the start of the text
+	TruncateEnd TruncateSide = iota
+	// TruncateStart keeps the end of the text
+	TruncateStart
+)
+
+// TruncateToTokens shortens text so that it is at most maxTokens tokens for the tokenizer model uses, removing whole
+// tokens from the given side. It returns the truncated text and how many tokens were dropped. A character that is
+// split across tokens is dropped completely rather than leaving part of it behind.
+func TruncateToTokens(model, text string, maxTokens int, side TruncateSide) (string, int, error) {
+	if maxTokens < 0 {
+		return "", 0, errors.New("maxTokens can't be negative")
+	}
+	enc, err := encodingForModel(model)
+	if err != nil {
+		return "", 0, err
+	}
+	tokens, err := enc.encode(text)
+	if err != nil {
+		return "", 0, err
+	}
+	dropped := len(tokens) - maxTokens
+	if dropped <= 0 {
+		return text, 0, nil
+	}
+
+	if side == TruncateStart {
+		kept := enc.decode(tokens[dropped:])
+		for len(kept) > 0 {
+			if r, size := utf8.DecodeRuneInString(kept); r != utf8.RuneError || size != 1 {
+				break
+			}
+			kept = kept[1:]
+		}
+		return kept, dropped, nil
+	}
+	kept := enc.decode(tokens[:maxTokens])
+	for len(kept) > 0 {
+		if r, size := utf8.DecodeLastRuneInString(kept); r != utf8.RuneError || size != 1 {
+			break
+		}
+		kept = kept[:len(kept)-1]
+	}
+	return kept, dropped, nil
+}
+
+// encodingNameForModel returns the name of the tiktoken encoding used by model
+func encodingNameForModel(model string) (string, error) {
+	switch model {
+	case AdaEngine, BabbageEngine, CurieEngine, DavinciEngine, DavinciInstructEngine,
+		TextAda001Engine, TextBabbage001Engine, TextCurie001Engine, TextDavinci001Engine:
+		return r50kBase, nil
+	case TextDavinci002Engine, TextDavinci003Engine:
+		return p50kBase, nil
+	case TextEmbeddingAda002:
+		return cl100kBase, nil
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5-turbo", "gpt-35-turbo", "text-embedding-3-"} {
+		if strings.HasPrefix(model, prefix) {
+			return cl100kBase, nil
+		}
+	}
+	for _, prefix := range []string{"code-davinci-", "code-cushman-", "text-davinci-edit-"} {
+		if strings.HasPrefix(model, prefix) {
+			return p50kBase, nil
+		}
+	}
+	for _, prefix := range []string{"text-similarity-", "text-search-", "code-search-"} {
+		if strings.HasPrefix(model, prefix) {
+			return r50kBase, nil
+		}
+	}
+	return "", fmt.Errorf("no tokenizer known for model %q", model)
+}
+
+// encodingForModel returns the encoding used by model, loading its ranks the first time it is used
+func encodingForModel(model string) (*encoding, error) {
+	name, err := encodingNameForModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+
+	var enc *encoding
+	switch name {
+	case cl100kBase:
+		enc, err = loadEncoding("assets/cl100k_base.tiktoken", cl100kPattern, math.MaxInt32)
+	case p50kBase:
+		enc, err = loadEncoding("assets/p50k_base.tiktoken", p50kPattern, math.MaxInt32)
+	case r50kBase:
+		enc, err = loadEncoding("assets/p50k_base.tiktoken", p50kPattern, r50kVocabSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	encodings[name] = enc
+	return enc, nil
+}
+
+// loadEncoding reads the tiktoken ranks file at path, where each line is a base64 encoded token and its rank.
+// Tokens ranked maxRank or higher are skipped.
+func loadEncoding(path, pattern string, maxRank int) (*encoding, error) {
+	data, err := tokenizerAssets.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	enc := &encoding{
+		pattern: regexp2.MustCompile(pattern, regexp2.None),
+		ranks:   make(map[string]int),
+		tokens:  make(map[int]string),
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid token in %s: %w", path, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank in %s: %w", path, err)
+		}
+		if rank >= maxRank {
+			continue
+		}
+		enc.ranks[string(token)] = rank
+		enc.tokens[rank] = string(token)
+	}
+	return enc, scanner.Err()
+}
+
+// encode splits text into tokens
+func (e *encoding) encode(text string) ([]int, error) {
+	var tokens []int
+	match, err := e.pattern.FindStringMatch(text)
+	for match != nil && err == nil {
+		piece := match.String()
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+		} else {
+			tokens = append(tokens, e.bytePairMerge(piece)...)
+		}
+		match, err = e.pattern.FindNextMatch(match)
+	}
+	return tokens, err
+}
+
+// bytePairMerge encodes a piece that isn't a single token by repeatedly merging the adjacent pair of parts with
+// the lowest rank, starting from single bytes
+func (e *encoding) bytePairMerge(piece string) []int {
+	// boundaries holds the start of each part, followed by the end of the piece
+	boundaries := make([]int, len(piece)+1)
+	for i := range boundaries {
+		boundaries[i] = i
+	}
+	pairRank := func(i int) int {
+		if i+2 >= len(boundaries) {
+			return math.MaxInt
+		}
+		if rank, ok := e.ranks[piece[boundaries[i]:boundaries[i+2]]]; ok {
+			return rank
+		}
+		return math.MaxInt
+	}
+
+	for len(boundaries) > 2 {
+		minRank, minIndex := math.MaxInt, -1
+		for i := 0; i < len(boundaries)-2; i++ {
+			if rank := pairRank(i); rank < minRank {
+				minRank, minIndex = rank, i
+			}
+		}
+		if minIndex < 0 {
+			break
+		}
+		boundaries = append(boundaries[:minIndex+1], boundaries[minIndex+2:]...)
+	}
+
+	tokens := make([]int, 0, len(boundaries)-1)
+	for i := 0; i < len(boundaries)-1; i++ {
+		tokens = append(tokens, e.ranks[piece[boundaries[i]:boundaries[i+1]]])
+	}
+	return tokens
+}
+
+// decode joins tokens back into text
+func (e *encoding) decode(tokens []int) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(e.tokens[token])
+	}
+	return sb.String()
+}
@@ -0,0 +1,166 @@
+package gpt3_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestCountTokens(t *testing.T) {
+	type testCase struct {
+		model    string
+		text     string
+		expected int
+	}
+
+	// expected counts are from OpenAI's tiktoken
+	testCases := []testCase{
+		{gpt3.GPT3Dot5Turbo, "", 0},
+		{gpt3.GPT3Dot5Turbo, "hello world", 2},
+		{gpt3.GPT3Dot5Turbo, "tiktoken is great!", 6},
+		{gpt3.GPT3Dot5Turbo, "    indented code\n\n\tfoo()", 8},
+		{gpt3.GPT3Dot5Turbo, "12345678", 3},
+		{gpt3.GPT3Dot5Turbo, "héllo 你好 🤖", 9},
+		{"gpt-4-0613", "hello world", 2},
+		{gpt3.TextEmbeddingAda002, "hello world", 2},
+		{gpt3.TextDavinci003Engine, "tiktoken is great!", 6},
+		{gpt3.TextDavinci003Engine, "    indented code\n\n\tfoo()", 8},
+		{gpt3.TextDavinci003Engine, "héllo 你好 🤖", 11},
+		{gpt3.DavinciEngine, "hello world", 2},
+		{gpt3.DavinciEngine, "    indented code\n\n\tfoo()", 10},
+		{gpt3.TextAda001Engine, "12345678", 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.model+" "+tc.text, func(t *testing.T) {
+			count, err := gpt3.CountTokens(tc.model, tc.text)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, count)
+		})
+	}
+}
+
+func TestCountTokensUnknownModel(t *testing.T) {
+	_, err := gpt3.CountTokens("unknown-model", "hello world")
+	assert.EqualError(t, err, `no tokenizer known for model "unknown-model"`)
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog"
+
+	truncated, dropped, err := gpt3.TruncateToTokens(gpt3.GPT3Dot5Turbo, text, 4, gpt3.TruncateEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, "The quick brown fox", truncated)
+	assert.Equal(t, 5, dropped)
+
+	truncated, dropped, err = gpt3.TruncateToTokens(gpt3.GPT3Dot5Turbo, text, 3, gpt3.TruncateStart)
+	assert.NoError(t, err)
+	assert.Equal(t, " the lazy dog", truncated)
+	assert.Equal(t, 6, dropped)
+
+	truncated, dropped, err = gpt3.TruncateToTokens(gpt3.GPT3Dot5Turbo, text, 100, gpt3.TruncateEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, text, truncated)
+	assert.Equal(t, 0, dropped)
+
+	truncated, dropped, err = gpt3.TruncateToTokens(gpt3.GPT3Dot5Turbo, text, 0, gpt3.TruncateEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, "", truncated)
+	assert.Equal(t, 9, dropped)
+
+	_, _, err = gpt3.TruncateToTokens(gpt3.GPT3Dot5Turbo, text, -1, gpt3.TruncateEnd)
+	assert.EqualError(t, err, "maxTokens can't be negative")
+}
+
+func TestTruncateToTokensSplitCharacter(t *testing.T) {
+	// the emoji is encoded as more than one token, so cutting through it must not leave invalid UTF-8
+	for maxTokens := 0; maxTokens <= 6; maxTokens++ {
+		for _, side := range []gpt3.TruncateSide{gpt3.TruncateEnd, gpt3.TruncateStart} {
+			truncated, _, err := gpt3.TruncateToTokens(gpt3.DavinciEngine, "a🤖b🤖c", maxTokens, side)
+			assert.NoError(t, err)
+			assert.True(t, utf8.ValidString(truncated), "%q", truncated)
+			count, err := gpt3.CountTokens(gpt3.DavinciEngine, truncated)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, count, maxTokens)
+		}
+	}
+}
+
+func TestModelContextWindow(t *testing.T) {
+	type testCase struct {
+		model     string
+		window    int
+		maxOutput int
+	}
+
+	testCases := []testCase{
+		{gpt3.DavinciEngine, 2049, 0},
+		{gpt3.GPT3Dot5Turbo, 4096, 0},
+		{"gpt-3.5-turbo-16k-0613", 16384, 0},
+		{"gpt-3.5-turbo-1106", 16385, 4096},
+		{"gpt-4-0613", 8192, 0},
+		{"gpt-4-32k", 32768, 0},
+		{"gpt-4-turbo-2024-04-09", 128000, 4096},
+		{"text-embedding-3-small", 8191, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.model, func(t *testing.T) {
+			window, ok := gpt3.ModelContextWindow(tc.model)
+			assert.True(t, ok)
+			assert.Equal(t, tc.window, window)
+
+			maxOutput, ok := gpt3.ModelMaxOutputTokens(tc.model)
+			assert.Equal(t, tc.maxOutput > 0, ok)
+			assert.Equal(t, tc.maxOutput, maxOutput)
+		})
+	}
+
+	for _, model := range []string{"unknown-model", "gpt-4-vision-preview", "gpt-4-1106-vision-preview"} {
+		window, ok := gpt3.ModelContextWindow(model)
+		assert.False(t, ok, model)
+		assert.Equal(t, 0, window, model)
+	}
+}
+
+func TestMaxCompletionTokens(t *testing.T) {
+	type testCase struct {
+		model    string
+		prompt   string
+		expected int
+	}
+
+	testCases := []testCase{
+		{gpt3.GPT3Dot5Turbo, "hello world", 4094},
+		{"gpt-4-0613", "hello world", 8190},
+		{"gpt-4-32k-0613", "hello world", 32766},
+		{gpt3.TextDavinci003Engine, "tiktoken is great!", 4091},
+		{gpt3.DavinciEngine, "", 2049},
+		{"gpt-4-1106-preview", "hello world", 4096},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.model, func(t *testing.T) {
+			remaining, err := gpt3.MaxCompletionTokens(tc.model, tc.prompt)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, remaining)
+		})
+	}
+
+	t.Run("prompt too long", func(t *testing.T) {
+		_, err := gpt3.MaxCompletionTokens(gpt3.AdaEngine, strings.Repeat(" hello", 2049))
+		assert.EqualError(t, err,
+			`prompt is 2049 tokens which doesn't fit the 2049 token context window of model "ada"`)
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		_, err := gpt3.MaxCompletionTokens("unknown-model", "hello world")
+		assert.EqualError(t, err, `no context window known for model "unknown-model"`)
+
+		_, err = gpt3.MaxCompletionTokens("gpt-4-vision-preview", "hello world")
+		assert.EqualError(t, err, `no context window known for model "gpt-4-vision-preview"`)
+	})
+}
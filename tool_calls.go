@@ -0,0 +1,51 @@
+package gpt3
+
+// ToolCallAccumulator reassembles the tool calls of a streamed chat completion choice. The deltas of a stream hold
+// fragments of the tool calls, with the ID and function name in the first fragment of a call and its arguments spread
+// over many, and the fragments of parallel tool calls are told apart by their Index. Use one accumulator per choice.
+// The zero value is ready to use.
+type ToolCallAccumulator struct {
+	calls []ToolCall
+}
+
+// Add adds the tool call fragments of delta, the Delta of a ChatCompletionStreamResponseChoice. Fragments without an
+// Index belong to the tool call at their position in delta.
+func (a *ToolCallAccumulator) Add(delta ChatCompletionResponseMessage) {
+	for i, fragment := range delta.ToolCalls {
+		index := i
+		if fragment.Index != nil {
+			index = *fragment.Index
+		}
+		if index < 0 {
+			continue
+		}
+		for len(a.calls) <= index {
+			a.calls = append(a.calls, ToolCall{})
+		}
+		call := &a.calls[index]
+		if fragment.ID != "" {
+			call.ID = fragment.ID
+		}
+		if fragment.Type != "" {
+			call.Type = fragment.Type
+		}
+		call.Function.Name += fragment.Function.Name
+		call.Function.Arguments += fragment.Function.Arguments
+	}
+}
+
+// Finish returns the tool calls added so far in the order of their Index, or nil when there are none. Their Index is
+// unset so they can be passed back in the ToolCalls of an assistant message.
+func (a *ToolCallAccumulator) Finish() []ToolCall {
+	if len(a.calls) == 0 {
+		return nil
+	}
+	calls := make([]ToolCall, len(a.calls))
+	copy(calls, a.calls)
+	for i := range calls {
+		if calls[i].Type == "" {
+			calls[i].Type = ToolTypeFunction
+		}
+	}
+	return calls
+}
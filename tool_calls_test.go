@@ -0,0 +1,60 @@
+package gpt3_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestToolCallAccumulator(t *testing.T) {
+	chunks := []string{
+		`{"role":"assistant","content":null,"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}`,
+		`{"tool_calls":[{"index":0,"function":{"arguments":"{\"loc"}}]}`,
+		`{"tool_calls":[{"index":0,"function":{"arguments":"ation\": \"Paris\"}"}}]}`,
+		`{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_","arguments":""}}]}`,
+		`{"tool_calls":[{"index":1,"function":{"name":"time","arguments":"{}"}}]}`,
+		`{}`,
+	}
+
+	var acc gpt3.ToolCallAccumulator
+	assert.Nil(t, acc.Finish())
+	for _, chunk := range chunks {
+		var delta gpt3.ChatCompletionResponseMessage
+		assert.NoError(t, json.Unmarshal([]byte(chunk), &delta))
+		acc.Add(delta)
+	}
+	calls := acc.Finish()
+	assert.Equal(t, []gpt3.ToolCall{
+		{
+			ID:       "call_1",
+			Type:     gpt3.ToolTypeFunction,
+			Function: gpt3.FunctionCall{Name: "get_weather", Arguments: `{"location": "Paris"}`},
+		},
+		{
+			ID:       "call_2",
+			Type:     gpt3.ToolTypeFunction,
+			Function: gpt3.FunctionCall{Name: "get_time", Arguments: "{}"},
+		},
+	}, calls)
+
+	data, err := json.Marshal(calls[1])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"call_2","type":"function","function":{"name":"get_time","arguments":"{}"}}`, string(data))
+}
+
+func TestToolCallAccumulatorWithoutIndex(t *testing.T) {
+	var acc gpt3.ToolCallAccumulator
+	acc.Add(gpt3.ChatCompletionResponseMessage{ToolCalls: []gpt3.ToolCall{
+		{ID: "call_1", Function: gpt3.FunctionCall{Name: "a", Arguments: "{"}},
+		{ID: "call_2", Function: gpt3.FunctionCall{Name: "b", Arguments: "{}"}},
+	}})
+	acc.Add(gpt3.ChatCompletionResponseMessage{ToolCalls: []gpt3.ToolCall{
+		{Function: gpt3.FunctionCall{Arguments: "}"}},
+	}})
+	assert.Equal(t, []gpt3.ToolCall{
+		{ID: "call_1", Type: gpt3.ToolTypeFunction, Function: gpt3.FunctionCall{Name: "a", Arguments: "{}"}},
+		{ID: "call_2", Type: gpt3.ToolTypeFunction, Function: gpt3.FunctionCall{Name: "b", Arguments: "{}"}},
+	}, acc.Finish())
+}
@@ -2,6 +2,9 @@ package gpt3
 
 import (
 	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
 	"math/big"
 	"strings"
 )
@@ -26,6 +29,16 @@ func IntPtr(i int) *int {
 	return &i
 }
 
+// StringPtr converts a string to an *string as a convenience
+func StringPtr(s string) *string {
+	return &s
+}
+
+// BoolPtr converts a bool to an *bool as a convenience
+func BoolPtr(b bool) *bool {
+	return &b
+}
+
 // intPtrDefault returns int ptr if not nil otherwise creates int with default value and returns pointer.
 func intPtrDefault(i *int, defaultValue int) *int {
 	if i == nil {
@@ -70,3 +83,39 @@ func random(min, max int64) int64 {
 
 	return n.Int64() + min
 }
+
+// DotProduct returns the dot product of two vectors of the same length
+func DotProduct(a, b []float32) (float32, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("vector lengths differ: %d != %d", len(a), len(b))
+	}
+
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return float32(sum), nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors of the same length, ranging from -1
+// (opposite) through 0 (orthogonal) to 1 (identical direction). OpenAI embeddings are normalized to length 1,
+// so for those this is equivalent to DotProduct.
+func CosineSimilarity(a, b []float32) (float32, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("vector lengths differ: %d != %d", len(a), len(b))
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0, errors.New("cosine similarity is undefined for zero magnitude vectors")
+	}
+
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB))), nil
+}
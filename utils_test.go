@@ -0,0 +1,60 @@
+package gpt3_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamjobot/go-gpt3"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	type testCase struct {
+		name     string
+		a        []float32
+		b        []float32
+		expected float32
+	}
+
+	testCases := []testCase{
+		{"Identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 1},
+		{"Same direction", []float32{1, 2, 3}, []float32{2, 4, 6}, 1},
+		{"Orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"Opposite", []float32{1, 2, 3}, []float32{-1, -2, -3}, -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := gpt3.CosineSimilarity(tc.a, tc.b)
+			assert.NoError(t, err)
+			assert.InDelta(t, tc.expected, result, 1e-6)
+		})
+	}
+
+	t.Run("Length mismatch", func(t *testing.T) {
+		_, err := gpt3.CosineSimilarity([]float32{1, 2}, []float32{1, 2, 3})
+		assert.EqualError(t, err, "vector lengths differ: 2 != 3")
+	})
+
+	t.Run("Zero magnitude", func(t *testing.T) {
+		_, err := gpt3.CosineSimilarity([]float32{0, 0}, []float32{1, 2})
+		assert.EqualError(t, err, "cosine similarity is undefined for zero magnitude vectors")
+	})
+}
+
+func TestDotProduct(t *testing.T) {
+	result, err := gpt3.DotProduct([]float32{1, 2, 3}, []float32{4, -5, 6})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(12), result)
+
+	_, err = gpt3.DotProduct([]float32{1}, nil)
+	assert.EqualError(t, err, "vector lengths differ: 1 != 0")
+}
+
+func TestEmbeddingSimilarityTo(t *testing.T) {
+	a := gpt3.Embedding{Embedding: []float32{0.6, 0.8}}
+	b := gpt3.Embedding{Embedding: []float32{0.8, 0.6}}
+
+	result, err := a.SimilarityTo(b)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.96, result, 1e-6)
+}